@@ -0,0 +1,182 @@
+// Package cache stores embedding vectors on disk so `gix split` does not
+// ask the provider again for hunks it has already seen.
+package cache
+
+import (
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultMaxBytes bounds the cache when the config does not.
+const DefaultMaxBytes = 64 << 20
+
+// Store is a content-addressed embedding cache. Each vector lives in its own
+// file named after the hash of the model and the embedded text, so entries
+// never go stale and concurrent runs cannot corrupt each other. Reads bump
+// the file's modification time, Evict removes the least recently used files
+// once the cache outgrows MaxBytes.
+type Store struct {
+	Dir      string
+	MaxBytes int64
+}
+
+// Stats describes the contents of the cache.
+type Stats struct {
+	Dir      string
+	Entries  int
+	Bytes    int64
+	MaxBytes int64
+}
+
+// Open returns the store under the user's cache dir, e.g.
+// ~/.cache/gix/embeddings. maxBytes <= 0 means DefaultMaxBytes.
+func Open(maxBytes int64) (*Store, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("user cache dir: %w", err)
+	}
+	if maxBytes <= 0 {
+		maxBytes = DefaultMaxBytes
+	}
+	return &Store{Dir: filepath.Join(base, "gix", "embeddings"), MaxBytes: maxBytes}, nil
+}
+
+// Get returns the cached vector for text embedded with model.
+func (s *Store) Get(model, text string) ([]float32, bool) {
+	path := s.path(model, text)
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 || len(data)%4 != 0 {
+		return nil, false
+	}
+
+	vec := make([]float32, len(data)/4)
+	for i := range vec {
+		vec[i] = math.Float32frombits(binary.LittleEndian.Uint32(data[i*4:]))
+	}
+
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return vec, true
+}
+
+// Put stores the vector for text embedded with model.
+func (s *Store) Put(model, text string, vec []float32) error {
+	data := make([]byte, len(vec)*4)
+	for i, v := range vec {
+		binary.LittleEndian.PutUint32(data[i*4:], math.Float32bits(v))
+	}
+
+	path := s.path(model, text)
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating cache dir: %w", err)
+	}
+
+	// write to a unique temp file first, a reader never sees half a vector
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache entry: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("saving cache entry: %w", err)
+	}
+	return nil
+}
+
+// Evict removes the least recently used entries until the cache fits in
+// MaxBytes.
+func (s *Store) Evict() error {
+	entries, total, err := s.entries()
+	if err != nil || total <= s.MaxBytes {
+		return err
+	}
+
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].modTime.Before(entries[j].modTime)
+	})
+	for _, e := range entries {
+		if total <= s.MaxBytes {
+			break
+		}
+		if err := os.Remove(e.path); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("evicting cache entry: %w", err)
+		}
+		total -= e.size
+	}
+	return nil
+}
+
+// Stats counts the entries in the cache.
+func (s *Store) Stats() (Stats, error) {
+	entries, total, err := s.entries()
+	if err != nil {
+		return Stats{}, err
+	}
+	return Stats{Dir: s.Dir, Entries: len(entries), Bytes: total, MaxBytes: s.MaxBytes}, nil
+}
+
+// Clear removes every entry.
+func (s *Store) Clear() error {
+	if err := os.RemoveAll(s.Dir); err != nil {
+		return fmt.Errorf("clearing cache: %w", err)
+	}
+	return nil
+}
+
+type entry struct {
+	path    string
+	size    int64
+	modTime time.Time
+}
+
+func (s *Store) entries() ([]entry, int64, error) {
+	var entries []entry
+	var total int64
+
+	err := filepath.WalkDir(s.Dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) {
+				return nil
+			}
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		info, err := d.Info()
+		if err != nil {
+			return nil // removed by a concurrent eviction
+		}
+		entries = append(entries, entry{path: path, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+		return nil
+	})
+	if err != nil {
+		return nil, 0, fmt.Errorf("reading cache: %w", err)
+	}
+	return entries, total, nil
+}
+
+// path spreads entries over 256 subdirectories by the first hash byte.
+func (s *Store) path(model, text string) string {
+	sum := sha256.Sum256([]byte(model + "\x00" + text))
+	name := hex.EncodeToString(sum[:])
+	return filepath.Join(s.Dir, name[:2], name[2:])
+}
@@ -0,0 +1,122 @@
+package cache
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T, maxBytes int64) *Store {
+	t.Helper()
+	return &Store{Dir: t.TempDir(), MaxBytes: maxBytes}
+}
+
+func TestStore_PutAndGet(t *testing.T) {
+	s := newTestStore(t, DefaultMaxBytes)
+	want := []float32{0.5, -1, 3.25}
+
+	if err := s.Put("openai/text-embedding-3-small", "hunk", want); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	got, ok := s.Get("openai/text-embedding-3-small", "hunk")
+	if !ok {
+		t.Fatal("expected a cache hit")
+	}
+	if len(got) != len(want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("expected %v, got %v", want, got)
+		}
+	}
+}
+
+func TestStore_KeyedByModel(t *testing.T) {
+	s := newTestStore(t, DefaultMaxBytes)
+	if err := s.Put("openai/a", "hunk", []float32{1}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+
+	if _, ok := s.Get("openai/b", "hunk"); ok {
+		t.Error("expected a miss for another model")
+	}
+	if _, ok := s.Get("openai/a", "other hunk"); ok {
+		t.Error("expected a miss for another text")
+	}
+}
+
+func TestStore_EvictsLeastRecentlyUsed(t *testing.T) {
+	s := newTestStore(t, 8) // two vectors of one float each
+	old := time.Now().Add(-time.Hour)
+
+	for i, text := range []string{"a", "b", "c"} {
+		if err := s.Put("m", text, []float32{float32(i)}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+		at := old.Add(time.Duration(i) * time.Minute)
+		if err := os.Chtimes(s.path("m", text), at, at); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// reading "a" makes "b" the least recently used entry
+	if _, ok := s.Get("m", "a"); !ok {
+		t.Fatal("expected a cache hit")
+	}
+	if err := s.Evict(); err != nil {
+		t.Fatalf("Evict: %v", err)
+	}
+
+	if _, ok := s.Get("m", "b"); ok {
+		t.Error("expected b to be evicted")
+	}
+	for _, text := range []string{"a", "c"} {
+		if _, ok := s.Get("m", text); !ok {
+			t.Errorf("expected %s to survive eviction", text)
+		}
+	}
+}
+
+func TestStore_StatsAndClear(t *testing.T) {
+	s := newTestStore(t, DefaultMaxBytes)
+	for _, text := range []string{"a", "b"} {
+		if err := s.Put("m", text, []float32{1, 2}); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+
+	stats, err := s.Stats()
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Entries != 2 || stats.Bytes != 16 {
+		t.Errorf("expected 2 entries of 16 bytes, got %+v", stats)
+	}
+
+	if err := s.Clear(); err != nil {
+		t.Fatalf("Clear: %v", err)
+	}
+	stats, err = s.Stats()
+	if err != nil {
+		t.Fatalf("Stats after Clear: %v", err)
+	}
+	if stats.Entries != 0 {
+		t.Errorf("expected an empty cache, got %+v", stats)
+	}
+}
+
+func TestStore_CorruptEntryIsAMiss(t *testing.T) {
+	s := newTestStore(t, DefaultMaxBytes)
+	if err := s.Put("m", "a", []float32{1}); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := os.WriteFile(s.path("m", "a"), []byte{1, 2, 3}, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := s.Get("m", "a"); ok {
+		t.Error("expected a truncated entry to be a miss")
+	}
+}
@@ -0,0 +1,172 @@
+// Package changelog renders conventional commits as a release section in
+// Keep a Changelog format, see https://keepachangelog.com/en/1.1.0/.
+package changelog
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ademajagon/gix/conventional"
+)
+
+// Header starts a new changelog file.
+const Header = `# Changelog
+
+All notable changes to this project are documented in this file.
+
+The format is based on [Keep a Changelog](https://keepachangelog.com/en/1.1.0/).
+`
+
+// Unreleased is the version heading of changes not released yet.
+const Unreleased = "Unreleased"
+
+// Section titles, in the order they are written.
+const (
+	SectionBreaking = "Breaking Changes"
+	SectionAdded    = "Added"
+	SectionChanged  = "Changed"
+	SectionFixed    = "Fixed"
+	SectionOther    = "Other"
+)
+
+var sectionOrder = []string{SectionBreaking, SectionAdded, SectionChanged, SectionFixed, SectionOther}
+
+// sectionOf returns the section commit is listed under, or "" for commits
+// that do not matter to users of a release, like chores and tests. Those
+// are listed under Other with all.
+func sectionOf(c conventional.Commit, all bool) string {
+	switch {
+	case c.Breaking:
+		return SectionBreaking
+	case c.Type == "feat":
+		return SectionAdded
+	case c.Type == "fix":
+		return SectionFixed
+	case c.Type == "perf", c.Type == "refactor", c.Type == "revert":
+		// a revert undoes a fix or feature, it rarely removes one
+		return SectionChanged
+	case all:
+		return SectionOther
+	}
+	return ""
+}
+
+// Entry is one line of a section.
+type Entry struct {
+	Scope string
+	Text  string
+	// Note is shown below the entry, e.g. the migration for a breaking
+	// change.
+	Note string
+}
+
+// Release is a version's section of the changelog.
+type Release struct {
+	Version string
+	Date    string // YYYY-MM-DD, empty for Unreleased
+	// Summary is an optional paragraph below the heading.
+	Summary  string
+	Sections map[string][]Entry
+}
+
+// NewRelease groups commits, newest first as git log lists them, into the
+// sections of version. Within a section entries are ordered by scope and
+// then oldest first. With all, commits of any type are listed, the ones
+// that are not user facing under Other.
+func NewRelease(version, date string, commits []conventional.Commit, all bool) *Release {
+	r := &Release{Version: version, Date: date, Sections: make(map[string][]Entry)}
+	for i := len(commits) - 1; i >= 0; i-- {
+		c := commits[i]
+		section := sectionOf(c, all)
+		if section == "" {
+			continue
+		}
+		r.Sections[section] = append(r.Sections[section], Entry{Scope: c.Scope, Text: c.Subject, Note: c.BreakingNote})
+	}
+	for _, entries := range r.Sections {
+		slices.SortStableFunc(entries, func(a, b Entry) int { return strings.Compare(a.Scope, b.Scope) })
+	}
+	return r
+}
+
+// Empty reports whether no commit made it into the release.
+func (r *Release) Empty() bool {
+	return len(r.Sections) == 0
+}
+
+// Heading is the line that starts the release, e.g. "## [v1.2.0] - 2026-03-01".
+func (r *Release) Heading() string {
+	if r.Date == "" {
+		return fmt.Sprintf("## [%s]", r.Version)
+	}
+	return fmt.Sprintf("## [%s] - %s", r.Version, r.Date)
+}
+
+// Markdown renders the release section.
+func (r *Release) Markdown() string {
+	return r.Heading() + "\n" + r.Body()
+}
+
+// Body renders the release without its heading, e.g. for a tag message.
+func (r *Release) Body() string {
+	var sb strings.Builder
+	if r.Summary != "" {
+		fmt.Fprintf(&sb, "\n%s\n", strings.TrimSpace(r.Summary))
+	}
+	for _, title := range sectionOrder {
+		entries := r.Sections[title]
+		if len(entries) == 0 {
+			continue
+		}
+		fmt.Fprintf(&sb, "\n### %s\n", title)
+		for _, e := range entries {
+			line := e.Text
+			if e.Scope != "" {
+				line = fmt.Sprintf("**%s:** %s", e.Scope, e.Text)
+			}
+			fmt.Fprintf(&sb, "- %s\n", line)
+			if e.Note != "" {
+				fmt.Fprintf(&sb, "  %s\n", e.Note)
+			}
+		}
+	}
+	return sb.String()
+}
+
+var releaseHeading = regexp.MustCompile(`(?m)^## \[([^\]]+)\]`)
+
+// Prepend adds release to the changelog content, above the newest release.
+// A section for the same version is replaced, and so is the Unreleased
+// section when a version is released. Empty content starts a new changelog
+// with Header.
+func Prepend(content string, release *Release) string {
+	section := release.Markdown()
+	if strings.TrimSpace(content) == "" {
+		return Header + "\n" + section
+	}
+
+	headings := releaseHeading.FindAllStringSubmatchIndex(content, -1)
+	for i, h := range headings {
+		version := content[h[2]:h[3]]
+		if version != release.Version && version != Unreleased {
+			continue
+		}
+		end := len(content)
+		if i+1 < len(headings) {
+			end = headings[i+1][0]
+		}
+		rest := strings.TrimLeft(content[end:], "\n")
+		if rest != "" {
+			section += "\n"
+		}
+		return content[:h[0]] + section + rest
+	}
+
+	if len(headings) == 0 {
+		return strings.TrimRight(content, "\n") + "\n\n" + section
+	}
+	at := headings[0][0]
+	return content[:at] + section + "\n" + content[at:]
+}
@@ -0,0 +1,162 @@
+package changelog
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ademajagon/gix/conventional"
+	"github.com/ademajagon/gix/provider"
+)
+
+// commits are newest first, like git log lists them.
+var commits = []conventional.Commit{
+	{Type: "docs", Subject: "describe changelog"},
+	{Type: "fix", Scope: "git", Subject: "quote paths"},
+	{Type: "feat", Scope: "split", Subject: "add --tui"},
+	{Type: "feat", Scope: "commit", Subject: "add --amend"},
+	{Type: "refactor", Scope: "config", Subject: "rename keys", Breaking: true, BreakingNote: "Run gix config migrate."},
+	{Subject: "Update README.md"},
+}
+
+func TestRelease_Markdown(t *testing.T) {
+	got := NewRelease("v1.0.0", "2026-10-19", commits, false).Markdown()
+	want := `## [v1.0.0] - 2026-10-19
+
+### Breaking Changes
+- **config:** rename keys
+  Run gix config migrate.
+
+### Added
+- **commit:** add --amend
+- **split:** add --tui
+
+### Fixed
+- **git:** quote paths
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+
+	all := NewRelease(Unreleased, "", commits, true).Markdown()
+	if !strings.HasPrefix(all, "## [Unreleased]\n") || !strings.Contains(all, "### Other\n- Update README.md\n- describe changelog\n") {
+		t.Errorf("with all:\n%s", all)
+	}
+}
+
+func TestSectionOf(t *testing.T) {
+	tests := []struct {
+		commit conventional.Commit
+		all    bool
+		want   string
+	}{
+		{commit: conventional.Commit{Type: "feat"}, want: SectionAdded},
+		{commit: conventional.Commit{Type: "fix"}, want: SectionFixed},
+		{commit: conventional.Commit{Type: "perf"}, want: SectionChanged},
+		{commit: conventional.Commit{Type: "refactor"}, want: SectionChanged},
+		// a revert undoes a change, it is not a removed feature
+		{commit: conventional.Commit{Type: "revert", Subject: "fix(git): quote paths"}, want: SectionChanged},
+		{commit: conventional.Commit{Type: "revert", Breaking: true}, want: SectionBreaking},
+		{commit: conventional.Commit{Type: "chore"}, want: ""},
+		{commit: conventional.Commit{Type: "chore"}, all: true, want: SectionOther},
+	}
+	for _, tt := range tests {
+		if got := sectionOf(tt.commit, tt.all); got != tt.want {
+			t.Errorf("sectionOf(%+v, %v) = %q, want %q", tt.commit, tt.all, got, tt.want)
+		}
+	}
+}
+
+func TestRelease_Empty(t *testing.T) {
+	if !NewRelease("v1", "", commits[:1], false).Empty() {
+		t.Error("docs only release should be empty")
+	}
+}
+
+func TestPrepend(t *testing.T) {
+	release := NewRelease("v1.1.0", "2026-10-19", commits[1:2], false)
+	section := release.Markdown()
+
+	t.Run("new file", func(t *testing.T) {
+		if got := Prepend("", release); got != Header+"\n"+section {
+			t.Errorf("got:\n%s", got)
+		}
+	})
+
+	t.Run("above newest release", func(t *testing.T) {
+		content := "# Changelog\n\n## [v1.0.0] - 2026-03-01\n### Added\n- x\n"
+		want := "# Changelog\n\n" + section + "\n## [v1.0.0] - 2026-03-01\n### Added\n- x\n"
+		if got := Prepend(content, release); got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("replaces unreleased", func(t *testing.T) {
+		content := "# Changelog\n\n## [Unreleased]\n### Fixed\n- old\n\n## [v1.0.0] - 2026-03-01\n- x\n"
+		want := "# Changelog\n\n" + section + "\n## [v1.0.0] - 2026-03-01\n- x\n"
+		if got := Prepend(content, release); got != want {
+			t.Errorf("got:\n%s\nwant:\n%s", got, want)
+		}
+	})
+
+	t.Run("replaces same version at the end", func(t *testing.T) {
+		content := "## [v1.1.0] - 2026-10-01\n- old\n"
+		if got := Prepend(content, release); got != section {
+			t.Errorf("got:\n%s\nwant:\n%s", got, section)
+		}
+	})
+}
+
+type answer string
+
+func (a answer) Complete(context.Context, provider.Request) (*provider.Response, error) {
+	return &provider.Response{Text: string(a)}, nil
+}
+
+func TestNewReleaseWithNotes(t *testing.T) {
+	// listed oldest first: 1 refactor!, 2 feat(commit), 3 feat(split), 4 fix
+	chat := answer(`{"summary": "Review commits full-screen.", "entries": [
+		{"commits": [2, 3], "text": "Amend commits and review them in a terminal UI"},
+		{"commits": [1], "text": "Config keys were renamed"}]}`)
+
+	r, err := NewReleaseWithNotes(context.Background(), chat, "v1.0.0", "2026-10-19", commits, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := r.Markdown()
+	want := `## [v1.0.0] - 2026-10-19
+
+Review commits full-screen.
+
+### Breaking Changes
+- **config:** Config keys were renamed
+  Run gix config migrate.
+
+### Added
+- Amend commits and review them in a terminal UI
+
+### Fixed
+- **git:** quote paths
+`
+	if got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestNotes_Validate(t *testing.T) {
+	for _, raw := range []string{
+		`{"summary": "", "entries": [{"commits": [5], "text": "x"}]}`,
+		`{"summary": "", "entries": [{"commits": [1], "text": "x"}, {"commits": [1], "text": "y"}]}`,
+		`{"summary": "", "entries": [{"commits": [], "text": "x"}]}`,
+		`{"summary": "", "entries": [{"commits": [1], "text": " "}]}`,
+	} {
+		n := &notes{commits: 4}
+		schema, err := provider.SchemaFor(n)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := provider.DecodeJSON(raw, schema, n); err == nil {
+			t.Errorf("%s: expected an error", raw)
+		}
+	}
+}
@@ -0,0 +1,136 @@
+package changelog
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ademajagon/gix/conventional"
+	"github.com/ademajagon/gix/provider"
+)
+
+// notes is the JSON the chat model is asked to return. Commit numbers are
+// 1-based as shown in the prompt.
+type notes struct {
+	Summary string `json:"summary" desc:"one or two sentences on what the release brings"`
+	Entries []struct {
+		Commits []int  `json:"commits" desc:"numbers of the commits this entry describes"`
+		Text    string `json:"text" desc:"one line in plain language"`
+	} `json:"entries"`
+
+	commits int // how many commits were numbered
+}
+
+// Validate checks every commit number is known and used at most once.
+func (n *notes) Validate() error {
+	n.Summary = strings.TrimSpace(n.Summary)
+	seen := make(map[int]bool)
+	for i := range n.Entries {
+		e := &n.Entries[i]
+		e.Text = strings.TrimSpace(e.Text)
+		if e.Text == "" {
+			return fmt.Errorf("entry %d has no text", i+1)
+		}
+		if len(e.Commits) == 0 {
+			return fmt.Errorf("entry %d lists no commits", i+1)
+		}
+		for _, id := range e.Commits {
+			if id < 1 || id > n.commits {
+				return fmt.Errorf("entry %d: commit %d does not exist (valid: 1-%d)", i+1, id, n.commits)
+			}
+			if seen[id] {
+				return fmt.Errorf("commit %d is in more than one entry", id)
+			}
+			seen[id] = true
+		}
+	}
+	return nil
+}
+
+// NewReleaseWithNotes is NewRelease with entries written by the chat model
+// for users of the release, and a summary. Commits the model leaves out
+// keep their subject.
+func NewReleaseWithNotes(ctx context.Context, c provider.Chat, version, date string, commits []conventional.Commit, all bool) (*Release, error) {
+	// oldest first, as they are numbered in the prompt
+	var listed []conventional.Commit
+	for i := len(commits) - 1; i >= 0; i-- {
+		if sectionOf(commits[i], all) != "" {
+			listed = append(listed, commits[i])
+		}
+	}
+	if len(listed) == 0 {
+		return NewRelease(version, date, nil, all), nil
+	}
+
+	n := &notes{commits: len(listed)}
+	if err := provider.GenerateReleaseNotes(ctx, c, summarizeCommits(listed), n); err != nil {
+		return nil, fmt.Errorf("generating release notes: %w", err)
+	}
+
+	r := &Release{Version: version, Date: date, Summary: n.Summary, Sections: make(map[string][]Entry)}
+	covered := make(map[int]bool)
+	for _, e := range n.Entries {
+		group := make([]conventional.Commit, len(e.Commits))
+		for i, id := range e.Commits {
+			group[i] = listed[id-1]
+			covered[id] = true
+		}
+		section, entry := noteEntry(group, all)
+		entry.Text = e.Text
+		r.Sections[section] = append(r.Sections[section], entry)
+	}
+	for i, commit := range listed {
+		if !covered[i+1] {
+			section, entry := noteEntry([]conventional.Commit{commit}, all)
+			r.Sections[section] = append(r.Sections[section], entry)
+		}
+	}
+	return r, nil
+}
+
+// noteEntry returns the section of the most important commit of group, and
+// an entry with the scope they share and their breaking notes.
+func noteEntry(group []conventional.Commit, all bool) (string, Entry) {
+	section := SectionOther
+	entry := Entry{Scope: group[0].Scope, Text: group[0].Subject}
+	var breaking []string
+	for _, c := range group {
+		if s := sectionOf(c, all); slices.Index(sectionOrder, s) < slices.Index(sectionOrder, section) {
+			section = s
+		}
+		if c.Scope != entry.Scope {
+			entry.Scope = ""
+		}
+		if c.BreakingNote != "" {
+			breaking = append(breaking, c.BreakingNote)
+		}
+	}
+	entry.Note = strings.Join(breaking, " ")
+	return section, entry
+}
+
+// summarizeCommits numbers commits for the prompt, with their bodies.
+func summarizeCommits(commits []conventional.Commit) string {
+	var sb strings.Builder
+	for i, c := range commits {
+		header := c.Subject
+		if c.Type != "" {
+			header = c.Type
+			if c.Scope != "" {
+				header += "(" + c.Scope + ")"
+			}
+			if c.Breaking {
+				header += "!"
+			}
+			header += ": " + c.Subject
+		}
+		fmt.Fprintf(&sb, "%d. %s\n", i+1, header)
+		if c.Body != "" {
+			for _, line := range strings.Split(c.Body, "\n") {
+				fmt.Fprintf(&sb, "   %s\n", line)
+			}
+		}
+	}
+	return sb.String()
+}
@@ -10,6 +10,8 @@ import (
 	"runtime"
 	"strings"
 	"time"
+
+	"github.com/ademajagon/gix/semver"
 )
 
 const (
@@ -194,26 +196,16 @@ func writeCache(path string, resp *CheckResponse) {
 	_ = os.WriteFile(path, data, 0o600)
 }
 
+// isNewer reports whether latest is a higher version than current. Either
+// not being a semantic version, like a dev build, is never newer.
 func isNewer(latest, current string) bool {
-	l := strings.TrimPrefix(latest, "v")
-	c := strings.TrimPrefix(current, "v")
-	if l == c {
+	l, err := semver.Parse(latest)
+	if err != nil {
 		return false
 	}
-
-	lp := parseSemver(l)
-	cp := parseSemver(c)
-	for i := range lp {
-		if lp[i] != cp[i] {
-			return lp[i] > cp[i]
-		}
+	c, err := semver.Parse(current)
+	if err != nil {
+		return false
 	}
-
-	return false
-}
-
-func parseSemver(s string) [3]int {
-	var major, minor, patch int
-	fmt.Sscanf(s, "%d.%d.%d", &major, &minor, &patch)
-	return [3]int{major, minor, patch}
+	return semver.Compare(l, c) > 0
 }
@@ -20,6 +20,9 @@ func TestIsNewer(t *testing.T) {
 		{"v1.0.0", "v2.0.0", false},
 		{"1.2.4", "1.2.3", true},
 		{"1.2.3", "1.2.3", false},
+		{"v1.3.0", "v1.3.0-rc.1", true},
+		{"v1.3.0-rc.2", "v1.3.0", false},
+		{"invalid", "v1.0.0", false},
 	}
 
 	for _, c := range cases {
@@ -30,23 +33,6 @@ func TestIsNewer(t *testing.T) {
 	}
 }
 
-func TestParseSemver(t *testing.T) {
-	cases := []struct {
-		input string
-		want  [3]int
-	}{
-		{"1.2.3", [3]int{1, 2, 3}},
-		{"0.0.1", [3]int{0, 0, 1}},
-		{"10.20.30", [3]int{10, 20, 30}},
-		{"invalid", [3]int{0, 0, 0}},
-	}
-	for _, c := range cases {
-		if got := parseSemver(c.input); got != c.want {
-			t.Errorf("parseSemver(%q) == %v, want %v", c.input, got, c.want)
-		}
-	}
-}
-
 func TestReadOrCreateSignature_CreateNew(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "gix_id")
@@ -0,0 +1,81 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ademajagon/gix/cache"
+	"github.com/ademajagon/gix/config"
+	"github.com/spf13/cobra"
+)
+
+var cacheCmd = &cobra.Command{
+	Use:   "cache",
+	Short: "Manage the embedding cache",
+	Long: `Manage the embedding cache used by gix split.
+
+Embedding vectors are cached on disk, keyed by provider, model and hunk text,
+so rerunning a split does not embed the same hunks again. The least recently
+used entries are evicted once the cache outgrows its limit (64 MB, or
+embedding_cache_mb in the config). Set disable_embedding_cache to turn the
+cache off.`,
+}
+
+var cacheStatsCmd = &cobra.Command{
+	Use:   "stats",
+	Short: "Show the size of the embedding cache",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheStats,
+}
+
+var cacheClearCmd = &cobra.Command{
+	Use:   "clear",
+	Short: "Remove all cached embeddings",
+	Args:  cobra.NoArgs,
+	RunE:  runCacheClear,
+}
+
+func init() {
+	cacheCmd.AddCommand(cacheStatsCmd)
+	cacheCmd.AddCommand(cacheClearCmd)
+	rootCmd.AddCommand(cacheCmd)
+}
+
+func openCache() (*cache.Store, error) {
+	cfg, _ := config.Load() // the cache works without a config file
+	return cache.Open(cfg.EmbeddingCacheBytes())
+}
+
+func runCacheStats(_ *cobra.Command, _ []string) error {
+	store, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	stats, err := store.Stats()
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Location: %s\n", stats.Dir)
+	fmt.Printf("Entries:  %d\n", stats.Entries)
+	fmt.Printf("Size:     %.1f MB of %.1f MB\n", megabytes(stats.Bytes), megabytes(stats.MaxBytes))
+	return nil
+}
+
+func runCacheClear(_ *cobra.Command, _ []string) error {
+	store, err := openCache()
+	if err != nil {
+		return err
+	}
+
+	if err := store.Clear(); err != nil {
+		return err
+	}
+
+	fmt.Println("Embedding cache cleared.")
+	return nil
+}
+
+func megabytes(n int64) float64 {
+	return float64(n) / (1 << 20)
+}
@@ -0,0 +1,158 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ademajagon/gix/changelog"
+	"github.com/ademajagon/gix/config"
+	"github.com/ademajagon/gix/conventional"
+	"github.com/ademajagon/gix/internal/git"
+	"github.com/ademajagon/gix/provider"
+	"github.com/ademajagon/gix/utils"
+	"github.com/spf13/cobra"
+)
+
+var changelogCmd = &cobra.Command{
+	Use:   "changelog [<from>..<to>]",
+	Short: "Write a CHANGELOG.md section from conventional commits",
+	Long: `Write a release section in Keep a Changelog format from the conventional
+commits in <from>..<to>, and prepend it to CHANGELOG.md.
+
+Without a range the commits since the last tag are used, <from> alone means
+<from>..HEAD. feat commits are listed under Added, fix under Fixed, perf,
+refactor and revert under Changed, ordered by scope. Commits marked with "!"
+or a BREAKING CHANGE footer come first, under Breaking Changes, with their
+notes. Other types are left out unless --all is given.
+
+The section is headed with --version, or with <to> when that is a tag, and
+otherwise goes under Unreleased. An existing section for the same version,
+or the Unreleased one, is replaced.
+
+With --ai the chat model rewrites the entries as release notes for users,
+with a short summary. Use --stdout to print the section instead of writing
+the file.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runChangelog,
+}
+
+var (
+	changelogVersion string
+	changelogFile    string
+	changelogStdout  bool
+	changelogAI      bool
+	changelogAll     bool
+)
+
+func init() {
+	changelogCmd.Flags().StringVar(&changelogVersion, "version", "", "Version heading of the section (default: <to> if it is a tag, else Unreleased)")
+	changelogCmd.Flags().StringVar(&changelogFile, "file", "", "Changelog to update (default: CHANGELOG.md at the repository root)")
+	changelogCmd.Flags().BoolVar(&changelogStdout, "stdout", false, "Print the section instead of updating the changelog")
+	changelogCmd.Flags().BoolVar(&changelogAI, "ai", false, "Let the chat model write the entries as release notes")
+	changelogCmd.Flags().BoolVar(&changelogAll, "all", false, "Also list docs, chore, test and other commits, under Other")
+	rootCmd.AddCommand(changelogCmd)
+}
+
+func runChangelog(cmd *cobra.Command, args []string) error {
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not a git repository")
+	}
+
+	var from, to string
+	if len(args) == 1 {
+		var found bool
+		if from, to, found = strings.Cut(args[0], ".."); !found {
+			from, to = args[0], ""
+		}
+	} else {
+		from = git.LastTag("HEAD")
+	}
+	if to == "" {
+		to = "HEAD"
+	}
+
+	rangeSpec := to
+	if from != "" {
+		rangeSpec = from + ".." + to
+	}
+	commits, err := conventional.Log(rangeSpec)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("no commits in %s", rangeSpec)
+	}
+
+	version, date := changelogVersion, time.Now().Format(time.DateOnly)
+	switch {
+	case version == "" && git.IsTag(to):
+		version = to
+		if date, err = git.CommitDate(to); err != nil {
+			return err
+		}
+	case version == "":
+		version, date = changelog.Unreleased, ""
+	}
+
+	release, err := newRelease(cmd, version, date, commits)
+	if err != nil {
+		return err
+	}
+	if release.Empty() {
+		return fmt.Errorf("no user-facing commits in %s, use --all to list every commit", rangeSpec)
+	}
+
+	if changelogStdout {
+		fmt.Print(release.Markdown())
+		return nil
+	}
+
+	path := changelogFile
+	if path == "" {
+		root, err := git.TopLevel()
+		if err != nil {
+			return err
+		}
+		path = filepath.Join(root, "CHANGELOG.md")
+	}
+	content, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	if err := os.WriteFile(path, []byte(changelog.Prepend(string(content), release)), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", path, err)
+	}
+	fmt.Printf("Updated %s with %s (%d commit(s)).\n", path, release.Heading(), len(commits))
+	return nil
+}
+
+// newRelease groups commits into the release, with release notes written by
+// the chat model for --ai. If the model fails the commit subjects are used.
+func newRelease(cmd *cobra.Command, version, date string, commits []conventional.Commit) (*changelog.Release, error) {
+	if !changelogAI {
+		return changelog.NewRelease(version, date, commits, changelogAll), nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	p, err := provider.NewChat(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	spinner := utils.NewSpinner()
+	spinner.Start()
+	release, err := changelog.NewReleaseWithNotes(cmd.Context(), p, version, date, commits, changelogAll)
+	spinner.Stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v, listing commit subjects instead\n", err)
+		return changelog.NewRelease(version, date, commits, changelogAll), nil
+	}
+	return release, nil
+}
@@ -2,46 +2,179 @@ package cmd
 
 import (
 	"bufio"
+	"context"
 	"fmt"
+	"io"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/ademajagon/gix/config"
 	"github.com/ademajagon/gix/internal/git"
 	"github.com/ademajagon/gix/provider"
+	"github.com/ademajagon/gix/trailer"
+	"github.com/ademajagon/gix/tui"
 	"github.com/ademajagon/gix/utils"
 	"github.com/spf13/cobra"
 )
 
 var commitCmd = &cobra.Command{
-	Use:   "commit",
+	Use:   "commit [<pathspec>...]",
 	Short: "Generate an AI commit message for staged changes",
 	Long: `Generate a conventional commit message for your staged git diff using AI.
   [Enter]   accept and commit
   e         open in $EDITOR
   r         regenerate (ask the AI again)
-  c         cancel`,
+  f         give feedback, e.g. "mention the migration" or "scope should be api"
+  c         cancel
+
+Feedback continues the conversation: the AI sees its earlier suggestions
+and everything you said about them. Type "f <feedback>" to skip the
+question.
+
+With --candidates N, N alternative messages are shown numbered: type a
+number to pick one, then accept or edit it as above.
+
+With --tui the diff is shown full-screen next to the suggestions, use the
+arrow keys or numbers to select one and the same letters as above. Without
+a terminal gix falls back to the prompts.
+
+With --amend the message describes HEAD together with the staged changes,
+like git commit --amend, and keeps the trailers of HEAD's message. A HEAD
+that was already pushed is only amended with --force.
+
+--co-author adds a Co-authored-by trailer. Pass an alias from co_authors
+in the config, part of the name or email of someone in the history, or
+"Name <email>". The trailers from the config, e.g. Signed-off-by or
+Change-Id, are added to every message.
+
+With -a every change to tracked files is committed, with pathspecs only
+the changes to those paths, leaving other staged changes staged, like git
+commit -a and git commit <paths>. Paths must be known to git. The index
+is only updated once the message is accepted, cancelling leaves it alone.
+
+Flags after "--" are passed on to git commit, after the commit_args from
+the config:
+
+  gix commit -- --signoff -S --author "Alice <alice@example.com>"`,
+	Args: cobra.ArbitraryArgs,
 	RunE: runCommit,
 }
 
+var (
+	commitCandidates int
+	commitTUI        bool
+	commitAmend      bool
+	commitForce      bool
+	commitCoAuthors  []string
+	commitAll        bool
+)
+
 func init() {
+	commitCmd.Flags().BoolVarP(&commitAll, "all", "a", false, "Stage all changes to tracked files first")
+	commitCmd.Flags().BoolVar(&commitAmend, "amend", false, "Replace HEAD with a commit of its changes and the staged ones")
+	commitCmd.Flags().BoolVar(&commitForce, "force", false, "Amend HEAD even if it was already pushed")
+	commitCmd.Flags().BoolVar(&commitTUI, "tui", false, "Review suggestions in a full-screen terminal UI")
+	commitCmd.Flags().StringArrayVar(&commitCoAuthors, "co-author", nil, "Add a Co-authored-by trailer (alias, name, email or \"Name <email>\"), can be repeated")
+	commitCmd.Flags().IntVar(&commitCandidates, "candidates", 1, "Number of alternative messages to choose from")
 	rootCmd.AddCommand(commitCmd)
 }
 
-func runCommit(cmd *cobra.Command, _ []string) error {
+func runCommit(cmd *cobra.Command, args []string) error {
 	if !git.IsGitRepo() {
 		return fmt.Errorf("not a git repository")
 	}
 
-	hasStaged, err := git.HasStagedChanges()
+	var gitArgs []string
+	if dash := cmd.ArgsLenAtDash(); dash >= 0 {
+		args, gitArgs = args[:dash], args[dash:]
+	}
+	if commitAll && len(args) > 0 {
+		return fmt.Errorf("paths with -a do not make sense")
+	}
+
+	index, scope, cleanup, err := commitScope(commitAll, args)
 	if err != nil {
-		return fmt.Errorf("checking staged changes: %w", err)
+		return err
 	}
-	if !hasStaged {
-		fmt.Fprintln(os.Stderr, "nothing to commit (no staged changes)")
-		return nil
+	defer cleanup()
+	return commit(cmd, gitArgs, index, scope)
+}
+
+// commitScope returns the index that holds what gix commit commits, and
+// the git commit arguments that commit the same. Without -a or paths that
+// is the user's index. Otherwise it is a private index staged the way git
+// commit -a or git commit --only <paths> stages it; git stages the user's
+// index itself once the message is accepted, so cancelling changes nothing.
+func commitScope(all bool, paths []string) (git.Index, []string, func(), error) {
+	if !all && len(paths) == 0 {
+		return git.Index{}, nil, func() {}, nil
+	}
+
+	dir, err := git.Dir()
+	if err != nil {
+		return git.Index{}, nil, nil, err
+	}
+	path := filepath.Join(dir, "gix", "commit-index")
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return git.Index{}, nil, nil, fmt.Errorf("creating index dir: %w", err)
+	}
+	cleanup := func() { _ = git.Index{Path: path}.Remove() }
+
+	if all {
+		index, err := git.CopyIndex(path)
+		if err == nil {
+			err = index.UpdateTracked()
+		}
+		if err != nil {
+			cleanup()
+			return git.Index{}, nil, nil, fmt.Errorf("staging changes: %w", err)
+		}
+		return index, []string{"--all"}, cleanup, nil
+	}
+
+	// like git commit, only files git knows about are committed
+	files, err := git.KnownFiles(paths...)
+	if err != nil {
+		return git.Index{}, nil, nil, err
+	}
+	head, _ := git.ResolveRef("HEAD")
+	index := git.Index{Path: path}
+	if err := index.ReadTree(head); err == nil {
+		err = index.UpdateFiles(files...)
+	}
+	if err != nil {
+		cleanup()
+		return git.Index{}, nil, nil, fmt.Errorf("staging changes: %w", err)
+	}
+	return index, append([]string{"--only", "--"}, paths...), cleanup, nil
+}
+
+// commit generates, reviews and commits the message for the changes
+// staged in index, see commitScope. scope goes after the other git commit
+// arguments.
+func commit(cmd *cobra.Command, gitArgs []string, index git.Index, scope []string) error {
+	if commitAmend {
+		if err := checkAmend(commitForce); err != nil {
+			return err
+		}
+	} else {
+		hasStaged, err := index.HasChanges()
+		if err != nil {
+			return fmt.Errorf("checking staged changes: %w", err)
+		}
+		if !hasStaged {
+			fmt.Fprintln(os.Stderr, "nothing to commit (no staged changes)")
+			return nil
+		}
+	}
+
+	if commitCandidates < 1 {
+		return fmt.Errorf("--candidates must be at least 1")
 	}
 
 	cfg, err := config.Load()
@@ -49,62 +182,304 @@ func runCommit(cmd *cobra.Command, _ []string) error {
 		return err
 	}
 
-	limit := git.MaxDiffBytesCloud
-	if cfg.ResolveProvider() == provider.ProviderOllama {
-		limit = git.MaxDiffBytesLocal
+	gitArgs = append(slices.Clone(cfg.CommitArgs), gitArgs...)
+	if err := checkCommitArgs(gitArgs); err != nil {
+		return err
+	}
+
+	trailers, err := commitTrailers(cfg, commitCoAuthors)
+	if err != nil {
+		return err
+	}
+	if commitAmend {
+		head, err := git.Message("HEAD")
+		if err != nil {
+			return err
+		}
+		kept, err := trailer.Parse(head)
+		if err != nil {
+			return err
+		}
+		trailers = append(kept, trailers...)
 	}
 
-	diff, err := git.GetStagedDiff(limit)
+	var diff string
+	if commitAmend {
+		diff, err = index.AmendDiff(chatDiffLimit(cfg))
+	} else {
+		diff, err = index.StagedDiff(chatDiffLimit(cfg))
+	}
 	if err != nil {
 		return fmt.Errorf("reading diff: %w", err)
 	}
 
-	p, err := provider.NewFromConfig(cfg)
+	p, err := provider.NewChat(cfg)
 	if err != nil {
 		return err
 	}
 
-	spinner := utils.NewSpinner()
-	spinner.Start()
-	suggestion, err := p.GenerateCommitMessage(diff)
-	spinner.Stop()
+	suggestions, err := suggestMessages(cmd.Context(), p, diff, commitCandidates)
 	if err != nil {
 		return fmt.Errorf("AI provider: %w", err)
 	}
 
-	finalMessage, err := promptMessage(suggestion, diff, p)
+	session := provider.NewCommitSession(p, diff)
+	finalMessage, err := reviewMessage(cmd.Context(), suggestions, diff, session)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "aborted")
 		return nil
 	}
 
-	gitCmd := exec.Command("git", "commit", "-m", finalMessage)
+	if finalMessage, err = trailer.Apply(finalMessage, trailers); err != nil {
+		return fmt.Errorf("adding trailers: %w", err)
+	}
+	if commitAmend {
+		gitArgs = append([]string{"--amend"}, gitArgs...)
+	}
+	return gitCommit(finalMessage, append(gitArgs, scope...))
+}
+
+// messageFlags set the commit message, which gix writes itself.
+var messageFlags = []string{
+	"-m", "--message", "-F", "--file", "-C", "--reuse-message",
+	"-c", "--reedit-message", "--fixup", "--squash", "-t", "--template",
+}
+
+// stagingFlags choose what is committed, which gix must know before it
+// reads the staged diff.
+var stagingFlags = []string{
+	"-a", "--all", "-i", "--include", "-o", "--only",
+	"-p", "--patch", "--interactive", "--pathspec-from-file",
+}
+
+// valueFlags take their value as the next argument unless it is joined
+// with "=".
+var valueFlags = []string{"--author", "--date", "--trailer", "--cleanup"}
+
+// checkCommitArgs rejects git commit flags that conflict with gix, and
+// paths, which gix commit takes before "--".
+func checkCommitArgs(args []string) error {
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "--" || arg == "-" || !strings.HasPrefix(arg, "-"):
+			return fmt.Errorf("%q is not a git commit flag, pass paths before --: gix commit <paths> -- <flags>", arg)
+		case strings.HasPrefix(arg, "--"):
+			name, _, joined := strings.Cut(arg, "=")
+			name = expandFlag(name)
+			if err := checkCommitFlag(name); err != nil {
+				return err
+			}
+			if !joined && slices.Contains(valueFlags, name) {
+				i++
+			}
+		default:
+			// short flags can be grouped, -sm<msg> is -s -m <msg>; -S and
+			// -u take the rest as their value
+			for _, c := range arg[1:] {
+				if err := checkCommitFlag("-" + string(c)); err != nil {
+					return err
+				}
+				if c == 'S' || c == 'u' {
+					break
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// expandFlag returns the long flag name abbreviates: git accepts any
+// unambiguous prefix, --mess is --message. The flags gix rejects are tried
+// first, so a prefix that could mean one of them is refused as well.
+func expandFlag(name string) string {
+	known := slices.Concat(messageFlags, stagingFlags, []string{"--amend"}, valueFlags)
+	if slices.Contains(known, name) {
+		return name
+	}
+	for _, flag := range known {
+		if strings.HasPrefix(flag, "--") && strings.HasPrefix(flag, name) {
+			return flag
+		}
+	}
+	return name
+}
+
+func checkCommitFlag(name string) error {
+	switch {
+	case slices.Contains(messageFlags, name):
+		return fmt.Errorf("%s sets the commit message, which gix generates", name)
+	case slices.Contains(stagingFlags, name):
+		return fmt.Errorf("%s chooses what to commit, use gix commit -a or gix commit <paths> instead", name)
+	case name == "--amend":
+		return fmt.Errorf("use gix commit --amend, so the message describes the amended commit")
+	}
+	return nil
+}
+
+// commitTrailers resolves the --co-author names and appends the trailers
+// from the config, see trailer.Apply.
+func commitTrailers(cfg config.Config, coAuthors []string) ([]string, error) {
+	var trailers []string
+	for _, name := range coAuthors {
+		t, err := trailer.CoAuthor(name, cfg.CoAuthors)
+		if err != nil {
+			return nil, err
+		}
+		trailers = append(trailers, t)
+	}
+	return append(trailers, cfg.Trailers...), nil
+}
+
+// gitCommit runs git commit with message and extra flags. The message goes
+// through a temp file with -F, so any length and characters are safe, and
+// is only cleaned up of whitespace: lines starting with "#" are kept.
+func gitCommit(message string, extra []string) error {
+	f, err := os.CreateTemp("", "gix-commit-*.txt")
+	if err != nil {
+		return fmt.Errorf("writing commit message: %w", err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(message + "\n"); err != nil {
+		f.Close()
+		return fmt.Errorf("writing commit message: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing commit message: %w", err)
+	}
+
+	gitCmd := exec.Command("git", append([]string{"commit", "--cleanup=whitespace", "-F", f.Name()}, extra...)...)
+	gitCmd.Stdin = os.Stdin
 	gitCmd.Stdout = os.Stdout
 	gitCmd.Stderr = os.Stderr
 	if err := gitCmd.Run(); err != nil {
 		return fmt.Errorf("git commit: %w", err)
 	}
+	return nil
+}
 
+// checkAmend refuses to amend when there is no HEAD, or HEAD was pushed
+// and force is not set.
+func checkAmend(force bool) error {
+	head, err := git.ResolveRef("HEAD")
+	if err != nil {
+		return fmt.Errorf("nothing to amend, the branch has no commits yet")
+	}
+	if force {
+		return nil
+	}
+	remotes, err := git.RemoteBranchesContaining(head)
+	if err != nil {
+		return err
+	}
+	if len(remotes) > 0 {
+		return fmt.Errorf("HEAD was already pushed to %s, use --force to amend it anyway", strings.Join(remotes, ", "))
+	}
 	return nil
 }
 
-// promptMessage runs the accept/edit/regenerate/cancel
-func promptMessage(initial, diff string, p provider.AIProvider) (string, error) {
-	reader := bufio.NewReader(os.Stdin)
-	msg := initial
+// chatDiffLimit is how much diff the configured chat provider is sent.
+func chatDiffLimit(cfg config.Config) int {
+	if cfg.ResolveChatProvider() == provider.ProviderOllama {
+		return git.MaxDiffBytesLocal
+	}
+	return git.MaxDiffBytesCloud
+}
+
+// suggestMessages generates the first suggestions for diff behind a
+// spinner: one deterministic message, or n diverse candidates.
+func suggestMessages(ctx context.Context, p provider.Chat, diff string, n int) ([]string, error) {
+	spinner := utils.NewSpinner()
+	spinner.Start()
+	defer spinner.Stop()
+
+	if n == 1 {
+		msg, err := provider.GenerateCommitMessage(ctx, p, diff)
+		if err != nil {
+			return nil, err
+		}
+		return []string{msg}, nil
+	}
+	return provider.GenerateCommitMessages(ctx, p, diff, n)
+}
+
+// reviewMessage lets the user choose the message, in the terminal UI when
+// asked for and available, otherwise with line prompts.
+func reviewMessage(ctx context.Context, suggestions []string, diff string, session *provider.CommitSession) (string, error) {
+	if commitTUI {
+		if t, err := tui.Open(); err == nil {
+			defer t.Close()
+			n := len(suggestions)
+			return tui.RunCommit(ctx, t, diff, suggestions, tui.CommitActions{
+				Regenerate: func(ctx context.Context) ([]string, error) { return session.Suggest(ctx, n) },
+				Feedback: func(ctx context.Context, previous, feedback string) ([]string, error) {
+					return session.Refine(ctx, previous, feedback, n)
+				},
+				Edit: utils.EditInEditor,
+			})
+		}
+	}
+	return promptMessage(ctx, os.Stdin, suggestions, session)
+}
+
+// promptMessage runs the pick/accept/edit/regenerate/feedback/cancel loop.
+// With more than one suggestion the user picks one first, regenerating
+// asks for as many new ones. Feedback goes to session, which keeps the
+// conversation for the whole prompt. When in ends, as with Ctrl-D or piped
+// input, the prompt is cancelled.
+func promptMessage(ctx context.Context, in io.Reader, suggestions []string, session *provider.CommitSession) (string, error) {
+	reader := bufio.NewReader(in)
+	n := len(suggestions)
 
-	displayMessage(initial)
+	var msg string
+	show := func(s []string) {
+		suggestions = s
+		msg = ""
+		if len(s) == 1 {
+			msg = s[0]
+			displayMessage(msg)
+		} else {
+			displayCandidates(s)
+		}
+	}
+	show(suggestions)
 
 	for {
-		fmt.Print("[Enter] commit  [e]dit  [r]egen  [c]ancel: ")
+		if msg == "" {
+			fmt.Printf("[1-%d] pick  [r]egen  [f]eedback  [c]ancel: ", len(suggestions))
+		} else {
+			fmt.Print("[Enter] commit  [e]dit  [r]egen  [f]eedback  [c]ancel: ")
+		}
 
-		raw, _ := reader.ReadString('\n')
-		input := strings.TrimSpace(strings.ToLower(raw))
+		input, err := readLine(reader)
+		if err != nil {
+			if msg == "" {
+				return "", fmt.Errorf("no message picked: %w", err)
+			}
+			return "", fmt.Errorf("cancelled: %w", err)
+		}
+		command, feedback, _ := strings.Cut(input, " ")
+
+		if msg == "" {
+			if i, err := strconv.Atoi(input); err == nil && i >= 1 && i <= len(suggestions) {
+				msg = suggestions[i-1]
+				displayMessage(msg)
+				continue
+			}
+		}
 
-		switch input {
+		switch strings.ToLower(command) {
 		case "":
+			if msg == "" {
+				fmt.Fprintln(os.Stderr, "pick a message by its number")
+				continue
+			}
 			return msg, nil
 		case "e":
+			if msg == "" {
+				fmt.Fprintln(os.Stderr, "pick a message by its number first")
+				continue
+			}
 			edited := utils.EditInEditor(msg)
 			if edited == "" {
 				fmt.Fprintln(os.Stderr, "commit message cannot be empty")
@@ -115,14 +490,41 @@ func promptMessage(initial, diff string, p provider.AIProvider) (string, error)
 		case "r":
 			spinner := utils.NewSpinner()
 			spinner.Start()
-			newMsg, err := p.GenerateCommitMessage(diff)
+			regenerated, err := session.Suggest(ctx, n)
 			spinner.Stop()
 			if err != nil {
 				fmt.Fprintf(os.Stderr, "regen failed: %v\n", err)
 				continue
 			}
-			msg = newMsg
-			displayMessage(msg)
+			show(regenerated)
+		case "f":
+			feedback = strings.TrimSpace(feedback)
+			if feedback == "" {
+				fmt.Print("feedback: ")
+				if feedback, err = readLine(reader); err != nil {
+					return "", fmt.Errorf("cancelled: %w", err)
+				}
+			}
+			if feedback == "" {
+				continue
+			}
+
+			// the model should see what the user saw: the message as
+			// edited, or every candidate when none is picked yet
+			previous := msg
+			if previous == "" {
+				previous = strings.Join(suggestions, "\n")
+			}
+
+			spinner := utils.NewSpinner()
+			spinner.Start()
+			refined, err := session.Refine(ctx, previous, feedback, n)
+			spinner.Stop()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "feedback failed: %v\n", err)
+				continue
+			}
+			show(refined)
 		case "c":
 			return "", fmt.Errorf("cancelled")
 
@@ -132,8 +534,29 @@ func promptMessage(initial, diff string, p provider.AIProvider) (string, error)
 	}
 }
 
+// readLine reads one trimmed line of input. A last line without a newline
+// is still returned; io.EOF only comes once nothing is left to read.
+func readLine(r *bufio.Reader) (string, error) {
+	raw, err := r.ReadString('\n')
+	switch {
+	case err == io.EOF && raw == "":
+		return "", fmt.Errorf("input closed")
+	case err != nil && err != io.EOF:
+		return "", err
+	}
+	return strings.TrimSpace(raw), nil
+}
+
 func displayMessage(msg string) {
 	fmt.Print("\n> ")
 	utils.TypingEffect(msg, 5*time.Millisecond)
 	fmt.Println()
 }
+
+func displayCandidates(suggestions []string) {
+	fmt.Println()
+	for i, s := range suggestions {
+		fmt.Printf("%d) %s\n", i+1, s)
+	}
+	fmt.Println()
+}
@@ -0,0 +1,300 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newRepo creates a repository in a temp dir, changes into it and isolates
+// git from the user's config.
+func newRepo(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, ".gitconfig"))
+	t.Setenv("GIT_AUTHOR_NAME", "Ada")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ada@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Ada")
+	t.Setenv("GIT_COMMITTER_EMAIL", "ada@example.com")
+	gitRun(t, "init", "-q", "-b", "main")
+}
+
+func gitRun(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func TestCheckAmend(t *testing.T) {
+	newRepo(t)
+	if err := checkAmend(false); err == nil || !strings.Contains(err.Error(), "no commits") {
+		t.Errorf("expected an unborn branch to be refused, got %v", err)
+	}
+
+	gitRun(t, "commit", "-q", "--allow-empty", "-m", "initial")
+	if err := checkAmend(false); err != nil {
+		t.Errorf("expected an unpushed HEAD to be amendable, got %v", err)
+	}
+
+	remote := t.TempDir()
+	gitRun(t, "init", "-q", "--bare", remote)
+	gitRun(t, "remote", "add", "origin", remote)
+	gitRun(t, "push", "-q", "origin", "main")
+	if err := checkAmend(false); err == nil || !strings.Contains(err.Error(), "pushed to origin/main") {
+		t.Errorf("expected a pushed HEAD to be refused, got %v", err)
+	}
+	if err := checkAmend(true); err != nil {
+		t.Errorf("expected --force to allow amending, got %v", err)
+	}
+}
+
+func TestCheckCommitArgs(t *testing.T) {
+	tests := []struct {
+		args    []string
+		wantErr string
+	}{
+		{args: nil},
+		{args: []string{"--signoff", "-S", "--no-verify", "-n", "-v"}},
+		{args: []string{"--author", "Alice <alice@example.com>", "--date=now", "--trailer", "Refs: #1"}},
+		{args: []string{"-sS", "-SABCDEF", "-uno", "--cleanup", "strip"}},
+		{args: []string{"-m", "msg"}, wantErr: "-m sets the commit message"},
+		{args: []string{"--message=msg"}, wantErr: "--message sets the commit message"},
+		{args: []string{"-mfoo"}, wantErr: "-m sets the commit message"},
+		{args: []string{"-sm", "x"}, wantErr: "-m sets the commit message"},
+		{args: []string{"-sF", "msg.txt"}, wantErr: "-F sets the commit message"},
+		{args: []string{"--fixup=HEAD"}, wantErr: "--fixup sets the commit message"},
+		{args: []string{"-a"}, wantErr: "-a chooses what to commit"},
+		{args: []string{"--all"}, wantErr: "--all chooses what to commit"},
+		{args: []string{"-sa"}, wantErr: "-a chooses what to commit"},
+		{args: []string{"-i"}, wantErr: "-i chooses what to commit"},
+		{args: []string{"--include"}, wantErr: "--include chooses what to commit"},
+		{args: []string{"-o"}, wantErr: "-o chooses what to commit"},
+		{args: []string{"--only"}, wantErr: "--only chooses what to commit"},
+		{args: []string{"--pathspec-from-file=paths.txt"}, wantErr: "--pathspec-from-file chooses what to commit"},
+		{args: []string{"README.md"}, wantErr: `"README.md" is not a git commit flag`},
+		{args: []string{"--signoff", "--", "README.md"}, wantErr: `"--" is not a git commit flag`},
+		{args: []string{"--author", "Alice <alice@example.com>", "cmd/"}, wantErr: `"cmd/" is not a git commit flag`},
+		{args: []string{"--amend"}, wantErr: "use gix commit --amend"},
+		{args: []string{"--mess=x"}, wantErr: "--message sets the commit message"},
+		{args: []string{"--fil", "msg.txt"}, wantErr: "--file sets the commit message"},
+		{args: []string{"--al"}, wantErr: "--all chooses what to commit"},
+		{args: []string{"--inc"}, wantErr: "--include chooses what to commit"},
+		{args: []string{"--on"}, wantErr: "--only chooses what to commit"},
+		{args: []string{"--pathspec-f=paths.txt"}, wantErr: "--pathspec-from-file chooses what to commit"},
+		{args: []string{"--am"}, wantErr: "use gix commit --amend"},
+		{args: []string{"--auth", "Alice <alice@example.com>", "--tra", "Refs: #1", "--allow-empty"}},
+	}
+	for _, tt := range tests {
+		err := checkCommitArgs(tt.args)
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("checkCommitArgs(%q): unexpected error %v", tt.args, err)
+		case tt.wantErr != "" && (err == nil || !strings.Contains(err.Error(), tt.wantErr)):
+			t.Errorf("checkCommitArgs(%q) = %v, want an error containing %q", tt.args, err, tt.wantErr)
+		}
+	}
+}
+
+func TestGitCommit_KeepsMessage(t *testing.T) {
+	newRepo(t)
+	// even with comment lines stripped by default
+	gitRun(t, "config", "commit.cleanup", "strip")
+
+	message := "feat: support #hashtags\n\n# not a comment\nFixes #12.\n\nCo-authored-by: Bob <bob@example.com>"
+	if err := gitCommit(message, []string{"--allow-empty", "-q"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := gitRun(t, "log", "-1", "--format=%B"); got != message {
+		t.Errorf("message = %q, want %q", got, message)
+	}
+}
+
+// scopeRepo commits a.txt, b.txt and c.txt, then stages a change to b.txt,
+// leaves changes to a.txt and c.txt unstaged and adds an untracked file.
+func scopeRepo(t *testing.T) {
+	t.Helper()
+	newRepo(t)
+	for _, name := range []string{"a.txt", "b.txt", "c.txt"} {
+		writeFile(t, name, name+"\n")
+	}
+	gitRun(t, "add", ".")
+	gitRun(t, "commit", "-qm", "initial")
+
+	writeFile(t, "b.txt", "b staged\n")
+	gitRun(t, "add", "b.txt")
+	writeFile(t, "a.txt", "a unstaged\n")
+	writeFile(t, "c.txt", "c unstaged\n")
+	writeFile(t, "new.txt", "untracked\n")
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// changedFiles lists the files the diff touches.
+func changedFiles(diff string) string {
+	var files []string
+	for _, line := range strings.Split(diff, "\n") {
+		if name, ok := strings.CutPrefix(line, "+++ b/"); ok {
+			files = append(files, name)
+		}
+	}
+	return strings.Join(files, " ")
+}
+
+func TestCommitScope_All(t *testing.T) {
+	scopeRepo(t)
+	index := gitRun(t, "write-tree")
+
+	scoped, args, cleanup, err := commitScope(true, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	diff, err := scoped.StagedDiff(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changedFiles(diff); got != "a.txt b.txt c.txt" {
+		t.Errorf("diff covers %q, want every tracked change", got)
+	}
+	if gitRun(t, "write-tree") != index {
+		t.Fatal("the index changed before committing")
+	}
+
+	if err := gitCommit("feat: all", append([]string{"-q"}, args...)); err != nil {
+		t.Fatal(err)
+	}
+	if got := gitRun(t, "show", "--format=", "--name-only", "HEAD"); got != "a.txt\nb.txt\nc.txt" {
+		t.Errorf("committed %q", got)
+	}
+	if got := gitRun(t, "status", "--porcelain"); got != "?? new.txt" {
+		t.Errorf("status after commit:\n%s", got)
+	}
+}
+
+func TestCommitScope_Paths(t *testing.T) {
+	scopeRepo(t)
+	index := gitRun(t, "write-tree")
+
+	scoped, args, cleanup, err := commitScope(false, []string{"a.txt"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cleanup()
+	diff, err := scoped.StagedDiff(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := changedFiles(diff); got != "a.txt" {
+		t.Errorf("diff covers %q, want only a.txt", got)
+	}
+	if gitRun(t, "write-tree") != index {
+		t.Fatal("the index changed before committing")
+	}
+
+	if err := gitCommit("feat: a", append([]string{"-q"}, args...)); err != nil {
+		t.Fatal(err)
+	}
+	if got := gitRun(t, "show", "--format=", "--name-only", "HEAD"); got != "a.txt" {
+		t.Errorf("committed %q, want only a.txt", got)
+	}
+	// b.txt stays staged, c.txt unstaged, as with git commit a.txt
+	if got := gitRun(t, "status", "--porcelain"); got != "M  b.txt\n M c.txt\n?? new.txt" {
+		t.Errorf("status after commit:\n%s", got)
+	}
+}
+
+func TestCommitScope_RejectsUntrackedPaths(t *testing.T) {
+	scopeRepo(t)
+	_, _, _, err := commitScope(false, []string{"a.txt", "new.txt"})
+	if err == nil || !strings.Contains(err.Error(), "did not match any file(s) known to git") {
+		t.Errorf("expected an untracked path to be refused, got %v", err)
+	}
+}
+
+func TestCommitScope_CancelLeavesIndexAlone(t *testing.T) {
+	scopeRepo(t)
+	index := gitRun(t, "write-tree")
+
+	for _, tt := range []struct {
+		all   bool
+		paths []string
+	}{{all: true}, {paths: []string{"c.txt"}}} {
+		all := tt.all
+		scoped, _, cleanup, err := commitScope(tt.all, tt.paths)
+		if err != nil {
+			t.Fatal(err)
+		}
+		cleanup()
+		if gitRun(t, "write-tree") != index {
+			t.Errorf("all=%v: the index changed", all)
+		}
+		if _, err := os.Stat(scoped.Path); !os.IsNotExist(err) {
+			t.Errorf("all=%v: private index left behind", all)
+		}
+	}
+	if got := gitRun(t, "status", "--porcelain"); got != "M a.txt\nM  b.txt\n M c.txt\n?? new.txt" {
+		t.Errorf("status after cancelling:\n%s", got)
+	}
+}
+
+func TestPromptMessage_InputClosed(t *testing.T) {
+	tests := []struct {
+		name        string
+		suggestions []string
+		input       string
+		want        string
+		wantErrSub  string
+	}{
+		{name: "nothing picked", suggestions: []string{"feat: a", "feat: b"}, wantErrSub: "no message picked"},
+		{name: "one suggestion", suggestions: []string{"feat: a"}, wantErrSub: "cancelled"},
+		{name: "feedback", suggestions: []string{"feat: a"}, input: "f\n", wantErrSub: "cancelled"},
+		{name: "picked, then closed", suggestions: []string{"feat: a", "feat: b"}, input: "2\n", wantErrSub: "cancelled"},
+		{name: "picked and accepted", suggestions: []string{"feat: a", "feat: b"}, input: "2\n\n", want: "feat: b"},
+		{name: "pick without newline", suggestions: []string{"feat: a", "feat: b"}, input: "1", wantErrSub: "cancelled"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			done := make(chan struct{})
+			var got string
+			var err error
+			go func() {
+				defer close(done)
+				got, err = promptMessage(context.Background(), strings.NewReader(tt.input), tt.suggestions, nil)
+			}()
+			select {
+			case <-done:
+			case <-time.After(5 * time.Second):
+				t.Fatal("the prompt did not stop when its input ended")
+			}
+
+			if tt.wantErrSub != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErrSub) {
+					t.Errorf("expected error containing %q, got %q, %v", tt.wantErrSub, got, err)
+				}
+				return
+			}
+			if err != nil || got != tt.want {
+				t.Errorf("got %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
@@ -2,11 +2,13 @@ package cmd
 
 import (
 	"bufio"
+	"errors"
 	"fmt"
 	"os"
 	"strings"
 
 	"github.com/ademajagon/gix/config"
+	"github.com/ademajagon/gix/provider"
 	"github.com/spf13/cobra"
 )
 
@@ -27,10 +29,19 @@ Examples:
 }
 
 var setProviderCmd = &cobra.Command{
-	Use:       "set-provider <openai|gemini>",
-	Short:     "Set the default AI provider",
+	Use:   "set-provider <openai|gemini|ollama|local>",
+	Short: "Set the default AI provider",
+	Long: `Set the AI provider.
+
+Without flags the provider is used for everything. Use --chat or --embed to
+pick a provider for commit messages and split plans, or for the embeddings
+gix split clusters hunks with, independently of the default.
+
+Examples:
+  gix config set-provider gemini            # everything on Gemini
+  gix config set-provider ollama --embed    # but embeddings from Ollama`,
 	Args:      cobra.ExactArgs(1),
-	ValidArgs: []string{"openai", "gemini", "ollama"},
+	ValidArgs: []string{"openai", "gemini", "ollama", "local"},
 	RunE:      runSetProvider,
 }
 
@@ -71,11 +82,19 @@ Example:
 	RunE: runSetOllamaModel,
 }
 
-var keyProvider string
+var (
+	keyProvider   string
+	providerChat  bool
+	providerEmbed bool
+)
 
 func init() {
 	setKeyCmd.Flags().StringVar(&keyProvider, "provider", "openai", "Provider to set the key for (openai, gemini)")
 
+	setProviderCmd.Flags().BoolVar(&providerChat, "chat", false, "Only use the provider for commit messages and split plans")
+	setProviderCmd.Flags().BoolVar(&providerEmbed, "embed", false, "Only use the provider for embeddings")
+	setProviderCmd.MarkFlagsMutuallyExclusive("chat", "embed")
+
 	configCmd.AddCommand(setKeyCmd)
 	configCmd.AddCommand(setProviderCmd)
 	configCmd.AddCommand(setUpdateCheckCmd)
@@ -116,18 +135,50 @@ func runSetKey(_ *cobra.Command, _ []string) error {
 
 func runSetProvider(_ *cobra.Command, args []string) error {
 	name := strings.ToLower(strings.TrimSpace(args[0]))
-	if name != "openai" && name != "gemini" && name != "ollama" {
+	if name != "openai" && name != "gemini" && name != "ollama" && name != "local" {
 		return fmt.Errorf("unknown provider %q", name)
 	}
 
 	cfg, _ := config.Load()
-	cfg.Provider = name
+
+	// refuse a provider lacking the capability, other errors such as a
+	// missing API key can still be fixed after choosing it
+	probe := cfg
+	probe.ChatProvider, probe.EmbedProvider = name, name
+	probe.DisableEmbeddingCache = true
+	if providerChat || !providerEmbed {
+		if _, err := provider.NewChat(probe); errors.Is(err, provider.ErrNoChatModel) {
+			return fmt.Errorf("provider %q has no chat model", name)
+		}
+	}
+	if providerEmbed || !providerChat {
+		if _, err := provider.NewEmbedder(probe); errors.Is(err, provider.ErrNoEmbeddingModel) {
+			return fmt.Errorf("provider %q has no embedding model", name)
+		}
+	}
+
+	switch {
+	case providerChat:
+		cfg.ChatProvider = name
+	case providerEmbed:
+		cfg.EmbedProvider = name
+	default:
+		cfg.Provider = name
+		cfg.ChatProvider, cfg.EmbedProvider = "", ""
+	}
 
 	if err := config.Save(cfg); err != nil {
 		return fmt.Errorf("saving config: %w", err)
 	}
 
-	fmt.Printf("Default provider set to %q\n", name)
+	switch {
+	case providerChat:
+		fmt.Printf("Chat provider set to %q\n", name)
+	case providerEmbed:
+		fmt.Printf("Embedding provider set to %q\n", name)
+	default:
+		fmt.Printf("Default provider set to %q\n", name)
+	}
 	return nil
 }
 
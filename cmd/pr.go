@@ -0,0 +1,87 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ademajagon/gix/config"
+	"github.com/ademajagon/gix/internal/git"
+	"github.com/ademajagon/gix/pr"
+	"github.com/ademajagon/gix/provider"
+	"github.com/ademajagon/gix/utils"
+	"github.com/spf13/cobra"
+)
+
+var prCmd = &cobra.Command{
+	Use:   "pr",
+	Short: "Write a pull request title and description for the current branch",
+	Long: `Write a pull request title and Markdown description from the commits
+and diff of the current branch since it diverged from its base.
+
+The base is --base, or the branch's upstream when it tracks another
+branch, or the remote's default branch, or main or master. The body
+follows the repository's pull request template, e.g.
+.github/pull_request_template.md, and otherwise has a summary, the
+changes, testing notes and risks.
+
+Nothing is sent to a hosting service: the title, a blank line and the body
+are printed, or written to --output, e.g.
+
+  gix pr -o pr.md && gh pr create --title "$(head -1 pr.md)" --body "$(tail -n +3 pr.md)"
+
+With the local provider the description is built from the commit messages
+alone.`,
+	Args: cobra.NoArgs,
+	RunE: runPR,
+}
+
+var (
+	prBase   string
+	prOutput string
+)
+
+func init() {
+	prCmd.Flags().StringVar(&prBase, "base", "", "Branch the pull request targets (default: upstream or default branch)")
+	prCmd.Flags().StringVarP(&prOutput, "output", "o", "", "Write the description to this file instead of stdout")
+	rootCmd.AddCommand(prCmd)
+}
+
+func runPR(cmd *cobra.Command, _ []string) error {
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not a git repository")
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+
+	branch, err := pr.Load(prBase, chatDiffLimit(cfg))
+	if err != nil {
+		return err
+	}
+
+	p, err := provider.NewChat(cfg)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "Describing %d commit(s) of %s against %s…\n", len(branch.Commits), branch.Name, branch.Base)
+	spinner := utils.NewSpinner()
+	spinner.Start()
+	desc, err := pr.Generate(cmd.Context(), p, branch)
+	spinner.Stop()
+	if err != nil {
+		return fmt.Errorf("AI provider: %w", err)
+	}
+
+	if prOutput == "" {
+		fmt.Print(desc.String())
+		return nil
+	}
+	if err := os.WriteFile(prOutput, []byte(desc.String()), 0o644); err != nil {
+		return fmt.Errorf("writing %s: %w", prOutput, err)
+	}
+	fmt.Fprintf(os.Stderr, "Wrote %s\n", prOutput)
+	return nil
+}
@@ -0,0 +1,136 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ademajagon/gix/config"
+	"github.com/ademajagon/gix/internal/git"
+	"github.com/ademajagon/gix/provider"
+	"github.com/ademajagon/gix/split"
+	"github.com/ademajagon/gix/trailer"
+	"github.com/spf13/cobra"
+)
+
+var rewordCmd = &cobra.Command{
+	Use:   "reword [<commit> | --range <base>..<tip>]",
+	Short: "Regenerate the messages of existing commits",
+	Long: `Regenerate the message of an existing commit, or of every commit in a
+range, from its diff.
+
+Each new message is reviewed like in gix commit before anything changes.
+The commits are then recreated with their own trees and authors on the
+current branch, like an interactive rebase that only rewords, and HEAD
+moves once at the end. The working tree and index are not touched.
+Trailers of the old messages, such as Change-Id, are kept.
+
+Merges are refused, and so are commits that were pushed or live on a
+protected branch (main and master, or protected_branches in the config)
+unless --force is given.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runReword,
+}
+
+var (
+	rewordRange string
+	rewordForce bool
+)
+
+func init() {
+	rewordCmd.Flags().StringVar(&rewordRange, "range", "", "Reword every commit in <base>..<tip>")
+	rewordCmd.Flags().BoolVar(&rewordForce, "force", false, "Rewrite commits even if they were pushed or are on a protected branch")
+	rootCmd.AddCommand(rewordCmd)
+}
+
+func runReword(cmd *cobra.Command, args []string) error {
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not a git repository")
+	}
+
+	var arg string
+	switch {
+	case rewordRange != "" && len(args) > 0:
+		return fmt.Errorf("pass either a commit or --range, not both")
+	case rewordRange != "":
+		arg = rewordRange
+	case len(args) > 0:
+		arg = args[0]
+	default:
+		arg = "HEAD"
+	}
+
+	base, tip, err := split.ResolveRange(arg)
+	if err != nil {
+		return err
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return err
+	}
+	if err := split.CheckRewrite(base, tip, cfg.Protected(), rewordForce); err != nil {
+		return err
+	}
+
+	commits, err := split.RangeCommits(base, tip)
+	if err != nil {
+		return err
+	}
+
+	p, err := provider.NewChat(cfg)
+	if err != nil {
+		return err
+	}
+
+	messages := make(map[string]string, len(commits))
+	for i, c := range commits {
+		old, err := git.Message(c)
+		if err != nil {
+			return err
+		}
+		diff, err := git.GetCommitDiff(c, chatDiffLimit(cfg))
+		if err != nil {
+			return fmt.Errorf("reading diff of %s: %w", c[:7], err)
+		}
+
+		subject, _, _ := strings.Cut(old, "\n")
+		fmt.Printf("\n[%d/%d] %s %s\n", i+1, len(commits), c[:7], subject)
+		suggestions, err := suggestMessages(cmd.Context(), p, diff, 1)
+		if err != nil {
+			return fmt.Errorf("AI provider: %w", err)
+		}
+		msg, err := reviewMessage(cmd.Context(), suggestions, diff, provider.NewCommitSession(p, diff))
+		if err != nil {
+			fmt.Fprintln(os.Stderr, "aborted, no commit was changed")
+			return nil
+		}
+		// keep trailers such as Change-Id, the diff does not know them
+		kept, err := trailer.Parse(old)
+		if err != nil {
+			return err
+		}
+		if msg, err = trailer.Apply(msg, kept); err != nil {
+			return fmt.Errorf("adding trailers: %w", err)
+		}
+		if msg != old {
+			messages[c] = msg
+		}
+	}
+
+	if len(messages) == 0 {
+		fmt.Println("\nNo message changed.")
+		return nil
+	}
+
+	head, err := git.ResolveRef("HEAD")
+	if err != nil {
+		return err
+	}
+	if err := split.RewordCommits(base, messages); err != nil {
+		return fmt.Errorf("rewording commits: %w", err)
+	}
+	fmt.Printf("\nReworded %d commit(s). The previous history is at %s (git reset --keep %s to go back).\n",
+		len(messages), head[:7], head[:7])
+	return nil
+}
@@ -1,71 +1,260 @@
 package cmd
 
 import (
+	"bufio"
+	"context"
 	"fmt"
 	"os"
+	"strings"
 
 	"github.com/ademajagon/gix/config"
 	"github.com/ademajagon/gix/internal/git"
 	"github.com/ademajagon/gix/provider"
 	"github.com/ademajagon/gix/split"
+	"github.com/ademajagon/gix/trailer"
+	"github.com/ademajagon/gix/tui"
 	"github.com/ademajagon/gix/utils"
 	"github.com/spf13/cobra"
 )
 
 var splitCmd = &cobra.Command{
-	Use:   "split",
+	Use:   "split [<commit> | <base>..<tip>]",
 	Short: "[BETA] Split staged changes into semantic atomic commits",
-	Long:  "[BETA] Split staged changes into multiple semantic commits using AI.",
-	RunE:  runSplit,
+	Long: `[BETA] Split staged changes into multiple semantic commits using AI.
+
+By default hunks are taken from the staged diff as git produces them, with
+three lines of context. Nearby but unrelated edits often end up in a single
+hunk that way. Use --granular to diff without context and split every hunk
+into minimal change blocks so they can land in different commits.
+
+Hunks are clustered by embedding similarity, boosted when they touch the same
+file or package, import each other's package or reference each other's
+symbols. Raise --threshold for smaller, tighter commits or lower it for fewer
+commits, or ask for an exact number with --groups.
+
+Strategies (--strategy):
+  embed    cluster hunks by embedding similarity (default)
+  llm      let the chat model group hunks and write the messages
+  hybrid   cluster by embeddings, then let the chat model refine the groups
+
+The llm and hybrid strategies fall back to embedding clusters if the chat
+model does not return a valid plan. If embedding fails, e.g. without network,
+hunks are clustered with built-in TF-IDF vectors over identifiers and paths
+instead. --offline uses those from the start and templates the messages from
+the changed files, so no provider or config is needed at all.
+
+Commits are ordered so that definitions land before their uses and go.mod
+requirements before the code importing them. Pass --verify with a command,
+e.g. --verify "go build ./...", to check every intermediate commit in a
+temporary worktree; failing plans are reordered where possible or rejected.
+
+Before anything is committed you can review the plan:
+  y         apply the commits
+  e         edit the plan in $EDITOR: move hunks between commits, merge,
+            split, reorder, reword or regenerate messages, drop hunks
+  v         view the hunks of every commit
+  n         cancel
+
+With --tui the plan is shown as a tree of commits next to the diff of the
+hunk under the cursor. Press space to grab a hunk, move it between commits
+with the arrow keys and space again to put it down; n moves it into a new
+commit, x leaves it unstaged, m rewords a commit, g regenerates its message
+and enter applies the plan.
+
+Commits are built in a private index with git plumbing: the working tree,
+untracked files and stash are never touched and HEAD only moves once every
+commit exists, so a failure or Ctrl-C leaves the repository as it was. Hunks
+that are not committed stay as unstaged changes. If gix itself dies mid-way,
+run gix split --continue to finish or gix split --abort to drop the split.
+Use gix undo to revert a finished split. Commit hooks do not run.
+
+To review a plan elsewhere, e.g. in a pull request, use --dry-run to print it
+as Markdown or JSON (--format), or --export <dir> to write one patch file per
+commit plus plan.json. Neither changes the repository. Apply the plan later,
+with the same HEAD and staged changes, using --apply-plan <dir>/plan.json.
+
+Pass a commit to split an existing commit instead of the index, or a range
+<base>..<tip> to regroup every change after base up to tip. The new commits
+replace the old ones on the current branch and the commits after them are
+replayed with git rebase; if that stops, continue or abort it as usual. Every
+hunk must land in a commit, so the branch ends with the same tree. Each new
+commit keeps the author and date of the oldest split commit that changed its
+files. Merges, pushed commits and protected branches (main and master unless
+protected_branches is set in the config) are refused, the latter two unless
+--force is given. Use gix undo to restore the branch.
+
+The trailers from the config and one Co-authored-by per --co-author are
+added to every commit, see gix commit.`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runSplit,
 }
 
+var (
+	splitGranular  bool
+	splitThreshold float64
+	splitGroups    int
+	splitStrategy  string
+	splitVerify    string
+	splitContinue  bool
+	splitAbort     bool
+	splitDryRun    bool
+	splitFormat    string
+	splitExport    string
+	splitApplyPlan string
+	splitForce     bool
+	splitOffline   bool
+	splitTUI       bool
+	splitCoAuthors []string
+)
+
 func init() {
+	splitCmd.Flags().BoolVar(&splitGranular, "granular", false, "Split hunks into minimal change blocks (diff with --unified=0)")
+	splitCmd.Flags().Float64Var(&splitThreshold, "threshold", 0, fmt.Sprintf("Minimum similarity (0-1) for hunks to share a commit (default %v, %v with local embeddings)", split.DefaultThreshold, split.LocalThreshold))
+	splitCmd.Flags().IntVar(&splitGroups, "groups", 0, "Target number of commits (overrides --threshold)")
+	splitCmd.Flags().StringVar(&splitStrategy, "strategy", split.StrategyEmbed, "Grouping strategy: embed, llm or hybrid")
+	splitCmd.Flags().StringVar(&splitVerify, "verify", "", "Command every intermediate commit must pass, e.g. \"go build ./...\"")
+	splitCmd.Flags().BoolVar(&splitContinue, "continue", false, "Resume a split interrupted by a crash")
+	splitCmd.Flags().BoolVar(&splitAbort, "abort", false, "Drop a split interrupted by a crash")
+	splitCmd.Flags().BoolVar(&splitDryRun, "dry-run", false, "Print the plan instead of applying it")
+	splitCmd.Flags().StringVar(&splitFormat, "format", "markdown", "Output format for --dry-run: markdown or json")
+	splitCmd.Flags().StringVar(&splitExport, "export", "", "Write one patch per commit and plan.json to this directory instead of applying")
+	splitCmd.Flags().StringVar(&splitApplyPlan, "apply-plan", "", "Apply a plan written by --dry-run --format json or --export")
+	splitCmd.Flags().BoolVar(&splitOffline, "offline", false, "Cluster with built-in local embeddings and template messages from file paths, no AI provider needed")
+	splitCmd.Flags().BoolVar(&splitTUI, "tui", false, "Review the plan in a full-screen terminal UI (falls back to prompts when not on a terminal)")
+	splitCmd.Flags().StringArrayVar(&splitCoAuthors, "co-author", nil, "Add a Co-authored-by trailer to every commit, can be repeated")
+	splitCmd.Flags().BoolVar(&splitForce, "force", false, "Rewrite commits even if they were pushed or are on a protected branch")
+	splitCmd.MarkFlagsMutuallyExclusive("continue", "abort", "apply-plan", "dry-run")
+	splitCmd.MarkFlagsMutuallyExclusive("continue", "abort", "apply-plan", "export")
 	rootCmd.AddCommand(splitCmd)
 }
 
-func runSplit(_ *cobra.Command, _ []string) error {
+func runSplit(cmd *cobra.Command, args []string) error {
 	if !git.IsGitRepo() {
 		return fmt.Errorf("not a git repository")
 	}
 
-	hasStaged, err := git.HasStagedChanges()
-	if err != nil {
-		return fmt.Errorf("checking staged changes: %w", err)
+	rewrite := len(args) == 1
+	if rewrite && (splitContinue || splitAbort || splitApplyPlan != "" || splitDryRun || splitExport != "") {
+		return fmt.Errorf("--continue, --abort, --apply-plan, --dry-run and --export only work on staged changes")
 	}
-	if !hasStaged {
-		fmt.Fprintln(os.Stderr, "nothing to split (no staged changes)")
+
+	switch {
+	case splitContinue:
+		if err := split.ContinueSplit(); err != nil {
+			return fmt.Errorf("continuing split: %w", err)
+		}
+		fmt.Println("\nSplit finished.")
+		return nil
+	case splitAbort:
+		if err := split.AbortSplit(); err != nil {
+			return fmt.Errorf("aborting split: %w", err)
+		}
+		fmt.Println("Split aborted.")
 		return nil
 	}
 
 	cfg, err := config.Load()
+	if err != nil && !splitOffline && splitApplyPlan == "" {
+		return err
+	}
+	trailers, err := commitTrailers(cfg, splitCoAuthors)
 	if err != nil {
 		return err
 	}
+	if splitApplyPlan != "" {
+		return applyPlan(splitApplyPlan, trailers)
+	}
+
+	if splitFormat != "markdown" && splitFormat != "json" {
+		return fmt.Errorf("unknown format %q (supported: markdown, json)", splitFormat)
+	}
+
+	if splitThreshold < 0 || splitThreshold > 1 {
+		return fmt.Errorf("--threshold must be between 0 and 1, got %v", splitThreshold)
+	}
+	if splitGroups < 0 {
+		return fmt.Errorf("--groups must not be negative, got %d", splitGroups)
+	}
+	switch splitStrategy {
+	case split.StrategyEmbed, split.StrategyLLM, split.StrategyHybrid:
+	default:
+		return fmt.Errorf("unknown strategy %q (supported: embed, llm, hybrid)", splitStrategy)
+	}
+
+	var base, tip string
+	if rewrite {
+		if base, tip, err = split.ResolveRange(args[0]); err != nil {
+			return err
+		}
+		if splitVerify != "" && base == "" {
+			return fmt.Errorf("--verify cannot check a split of the root commit")
+		}
+	} else {
+		hasStaged, err := git.HasStagedChanges()
+		if err != nil {
+			return fmt.Errorf("checking staged changes: %w", err)
+		}
+		if !hasStaged {
+			fmt.Fprintln(os.Stderr, "nothing to split (no staged changes)")
+			return nil
+		}
+	}
+
+	if rewrite {
+		if err := split.CheckRewrite(base, tip, cfg.Protected(), splitForce); err != nil {
+			return fmt.Errorf("cannot split %s: %w", args[0], err)
+		}
+		if err := split.CheckReplay(tip); err != nil {
+			return fmt.Errorf("cannot split %s: %w", args[0], err)
+		}
+	}
 
 	limit := git.MaxDiffBytesCloud
-	if cfg.ResolveProvider() == provider.ProviderOllama {
+	if cfg.ResolveChatProvider() == provider.ProviderOllama || cfg.ResolveEmbedProvider() == provider.ProviderOllama {
 		limit = git.MaxDiffBytesLocal
 	}
 
-	hunks, err := git.ParseHunks(limit)
+	parse := func(unified int) ([]git.Hunk, error) {
+		if rewrite {
+			return git.ParseRevisionHunks(base, tip, limit, unified)
+		}
+		return git.ParseHunksUnified(limit, unified)
+	}
+
+	var hunks []git.Hunk
+	if splitGranular {
+		// git already emits one hunk per change block at -U0, SplitHunks
+		// only guards against hunks it still merges
+		hunks, err = parse(0)
+		hunks = git.SplitHunks(hunks)
+	} else {
+		hunks, err = parse(3)
+	}
 	if err != nil {
 		return fmt.Errorf("parsing hunks: %w", err)
 	}
 	if len(hunks) == 0 {
-		fmt.Fprintln(os.Stderr, "no hunks found in staged diff")
+		fmt.Fprintln(os.Stderr, "no hunks found to split")
 		return nil
 	}
 
-	p, err := provider.NewFromConfig(cfg)
-	if err != nil {
-		return err
+	var p provider.AIProvider = provider.NewLocal()
+	if !splitOffline {
+		if p, err = provider.NewFromConfig(cfg); err != nil {
+			return err
+		}
 	}
 
-	fmt.Printf("[BETA] Analysing %d hunk(s)…\n", len(hunks))
+	fmt.Fprintf(os.Stderr, "[BETA] Analysing %d hunk(s)…\n", len(hunks))
 
 	spinner := utils.NewSpinner()
 	spinner.Start()
-	groups, err := split.ClusterHunks(p, hunks)
+	groups, err := split.GroupHunks(cmd.Context(), p, hunks, split.Options{
+		Threshold: splitThreshold,
+		Groups:    splitGroups,
+		Strategy:  splitStrategy,
+		Progress:  func(pr split.Progress) { spinner.SetMessage(pr.String()) },
+	})
 	spinner.Stop()
 	if err != nil {
 		return fmt.Errorf("clustering hunks: %w", err)
@@ -76,23 +265,246 @@ func runSplit(_ *cobra.Command, _ []string) error {
 		return nil
 	}
 
-	fmt.Printf("\nProposed %d commit(s):\n", len(groups))
-	for i, g := range groups {
-		fmt.Printf("  %d. %s (%d hunk(s))\n", i+1, g.Message, len(g.Hunks))
+	groups = split.OrderGroups(groups)
+
+	verifyBase := "HEAD"
+	if rewrite {
+		verifyBase = base
+	}
+	if splitVerify != "" {
+		groups, err = split.VerifyPlan(verifyBase, groups, splitVerify)
+		if err != nil {
+			return fmt.Errorf("verifying commits: %w", err)
+		}
 	}
 
-	fmt.Print("\nApply these commits? [y/N]: ")
-	var answer string
-	fmt.Scanln(&answer)
-	if answer != "y" && answer != "Y" {
+	if splitDryRun || splitExport != "" {
+		return outputPlan(groups)
+	}
+
+	groups, dropped, err := reviewPlan(cmd.Context(), p, groups, hunks, rewrite, verifyBase)
+	if err != nil {
 		fmt.Fprintln(os.Stderr, "aborted")
 		return nil
 	}
+	if err := addTrailers(groups, trailers); err != nil {
+		return err
+	}
+
+	if rewrite {
+		if err := split.RewriteCommits(base, tip, groups); err != nil {
+			return fmt.Errorf("rewriting commits: %w", err)
+		}
+		fmt.Printf("\nSplit %s into %d commit(s).\n", args[0], len(groups))
+		return nil
+	}
 
 	if err := split.ApplyGroups(groups); err != nil {
 		return fmt.Errorf("applying commits: %w", err)
 	}
 
 	fmt.Printf("\nCreated %d commit(s).\n", len(groups))
+	if len(dropped) > 0 {
+		fmt.Printf("%d hunk(s) left unstaged.\n", len(dropped))
+	}
 	return nil
 }
+
+// outputPlan prints and/or exports the plan without touching the repository.
+func outputPlan(groups []split.HunkGroup) error {
+	plan, err := split.NewPlan(groups, nil)
+	if err != nil {
+		return err
+	}
+
+	if splitDryRun {
+		if splitFormat == "json" {
+			data, err := plan.JSON()
+			if err != nil {
+				return err
+			}
+			os.Stdout.Write(data)
+		} else {
+			fmt.Print(plan.Markdown())
+		}
+	}
+
+	if splitExport != "" {
+		paths, err := plan.Export(splitExport)
+		if err != nil {
+			return fmt.Errorf("exporting plan: %w", err)
+		}
+		for _, path := range paths {
+			fmt.Fprintln(os.Stderr, path)
+		}
+	}
+
+	return nil
+}
+
+// applyPlan applies a plan saved earlier, after checking it still matches
+// HEAD and the staged changes.
+func applyPlan(path string, trailers []string) error {
+	plan, err := split.LoadPlan(path)
+	if err != nil {
+		return err
+	}
+	if err := plan.Check(); err != nil {
+		return fmt.Errorf("cannot apply %s: %w", path, err)
+	}
+	if err := addTrailers(plan.Commits, trailers); err != nil {
+		return err
+	}
+
+	printPlan(plan.Commits, plan.Dropped)
+	fmt.Println()
+
+	if err := split.ApplyGroups(plan.Commits); err != nil {
+		return fmt.Errorf("applying commits: %w", err)
+	}
+
+	fmt.Printf("\nCreated %d commit(s).\n", len(plan.Commits))
+	if len(plan.Dropped) > 0 {
+		fmt.Printf("%d hunk(s) left unstaged.\n", len(plan.Dropped))
+	}
+	return nil
+}
+
+// addTrailers adds trailers to the message of every group.
+func addTrailers(groups []split.HunkGroup, trailers []string) error {
+	for i := range groups {
+		msg, err := trailer.Apply(groups[i].Message, trailers)
+		if err != nil {
+			return fmt.Errorf("adding trailers: %w", err)
+		}
+		groups[i].Message = msg
+	}
+	return nil
+}
+
+// reviewPlan shows the proposed commits and runs the apply/edit/view/cancel
+// loop. Editing opens the plan as a todo list in $EDITOR, see split.FormatTodo.
+// With keepAll set, edits that drop hunks are rejected. Edited plans are
+// ordered again, and verified against verifyBase with --verify.
+func reviewPlan(ctx context.Context, p provider.Chat, groups []split.HunkGroup, hunks []git.Hunk, keepAll bool, verifyBase string) ([]split.HunkGroup, []git.Hunk, error) {
+	if splitTUI {
+		if t, err := tui.Open(); err == nil {
+			groups, dropped, err := tui.RunSplit(ctx, t, groups, keepAll, tui.SplitActions{
+				Reword: utils.EditInEditor,
+				FillMessages: func(ctx context.Context, groups []split.HunkGroup) error {
+					return split.FillMessages(ctx, p, groups, split.Options{})
+				},
+			})
+			t.Close()
+			if err != nil {
+				return nil, nil, err
+			}
+			if groups, err = orderPlan(groups, verifyBase); err != nil {
+				fmt.Fprintf(os.Stderr, "invalid plan: %v\n", err)
+				return nil, nil, err
+			}
+			return groups, dropped, nil
+		}
+	}
+
+	reader := bufio.NewReader(os.Stdin)
+	var dropped []git.Hunk
+
+	printPlan(groups, dropped)
+
+	for {
+		fmt.Print("\nApply these commits? [y]es  [e]dit plan  [v]iew hunks  [N]o: ")
+
+		raw, _ := reader.ReadString('\n')
+		input := strings.TrimSpace(strings.ToLower(raw))
+
+		switch input {
+		case "y", "yes":
+			return groups, dropped, nil
+		case "e":
+			edited := utils.EditInEditor(split.FormatTodo(groups, dropped, hunks))
+			newGroups, newDropped, err := split.ParseTodo(edited, hunks)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "invalid plan: %v\n", err)
+				continue
+			}
+			if len(newGroups) == 0 {
+				fmt.Fprintln(os.Stderr, "plan has no commits, keeping the previous one")
+				continue
+			}
+			if keepAll && len(newDropped) > 0 {
+				fmt.Fprintln(os.Stderr, "every hunk must stay in a commit when splitting existing commits, keeping the previous plan")
+				continue
+			}
+
+			spinner := utils.NewSpinner()
+			spinner.Start()
+			err = split.FillMessages(ctx, p, newGroups, split.Options{
+				Progress: func(pr split.Progress) { spinner.SetMessage(pr.String()) },
+			})
+			spinner.Stop()
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "regen failed: %v\n", err)
+				continue
+			}
+			if newGroups, err = orderPlan(newGroups, verifyBase); err != nil {
+				fmt.Fprintf(os.Stderr, "invalid plan: %v\n", err)
+				continue
+			}
+
+			groups, dropped = newGroups, newDropped
+			printPlan(groups, dropped)
+		case "v":
+			viewPlan(groups, dropped)
+		case "", "n", "no":
+			return nil, nil, fmt.Errorf("cancelled")
+
+		default:
+			fmt.Fprintln(os.Stderr, "invalid input")
+		}
+	}
+}
+
+// orderPlan puts a plan the user edited back in dependency order, so no
+// commit comes before one it needs, and runs --verify on it again.
+func orderPlan(groups []split.HunkGroup, verifyBase string) ([]split.HunkGroup, error) {
+	groups = split.OrderGroups(groups)
+	if splitVerify == "" {
+		return groups, nil
+	}
+	verified, err := split.VerifyPlan(verifyBase, groups, splitVerify)
+	if err != nil {
+		return nil, fmt.Errorf("verifying commits: %w", err)
+	}
+	return verified, nil
+}
+
+func printPlan(groups []split.HunkGroup, dropped []git.Hunk) {
+	fmt.Printf("\nProposed %d commit(s):\n", len(groups))
+	for i, g := range groups {
+		fmt.Printf("  %d. %s (%d hunk(s))\n", i+1, g.Message, len(g.Hunks))
+	}
+	if len(dropped) > 0 {
+		fmt.Printf("  %d hunk(s) will be left unstaged\n", len(dropped))
+	}
+}
+
+func viewPlan(groups []split.HunkGroup, dropped []git.Hunk) {
+	printHunks := func(hunks []git.Hunk) {
+		for _, h := range hunks {
+			fmt.Printf("\n  %s\n  %s\n", h.FilePath, h.Header)
+			for _, l := range h.Lines() {
+				fmt.Printf("  %s\n", l)
+			}
+		}
+	}
+
+	for i, g := range groups {
+		fmt.Printf("\n── %d. %s\n", i+1, g.Message)
+		printHunks(g.Hunks)
+	}
+	if len(dropped) > 0 {
+		fmt.Println("\n── left unstaged")
+		printHunks(dropped)
+	}
+}
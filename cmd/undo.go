@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/ademajagon/gix/internal/git"
+	"github.com/ademajagon/gix/split"
+	"github.com/spf13/cobra"
+)
+
+var undoCmd = &cobra.Command{
+	Use:   "undo",
+	Short: "Undo the last gix split",
+	Long: `Undo the last gix split.
+
+HEAD and the index are restored to their state before the split, so the
+changes are staged again exactly as they were. The working tree is not
+touched. Refuses if HEAD moved since the split.`,
+	Args: cobra.NoArgs,
+	RunE: runUndo,
+}
+
+func init() {
+	rootCmd.AddCommand(undoCmd)
+}
+
+func runUndo(_ *cobra.Command, _ []string) error {
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not a git repository")
+	}
+
+	if err := split.Undo(); err != nil {
+		return fmt.Errorf("undo: %w", err)
+	}
+
+	fmt.Println("Split undone, changes are staged again.")
+	return nil
+}
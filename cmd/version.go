@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/ademajagon/gix/changelog"
+	"github.com/ademajagon/gix/config"
+	"github.com/ademajagon/gix/conventional"
+	"github.com/ademajagon/gix/internal/git"
+	"github.com/ademajagon/gix/provider"
+	"github.com/ademajagon/gix/semver"
+	"github.com/ademajagon/gix/utils"
+	"github.com/spf13/cobra"
+)
+
+var versionCmd = &cobra.Command{
+	Use:   "version",
+	Short: "Print the gix version, or compute the next version of a repository",
+	Args:  cobra.NoArgs,
+	Run: func(*cobra.Command, []string) {
+		fmt.Println(version)
+	},
+}
+
+var versionNextCmd = &cobra.Command{
+	Use:   "next",
+	Short: "Compute the next semantic version from conventional commits",
+	Long: `Compute the next semantic version from the conventional commits since
+the last release tag reachable from HEAD, and print it.
+
+A breaking change, marked with "!" or a BREAKING CHANGE footer, bumps the
+major version, a feat commit the minor version and anything else the patch
+version. Without a tag the repository starts at 0.0.0.
+
+With --pre rc the next version is a pre-release: v1.2.3 with a feature
+becomes v1.3.0-rc.1, and v1.3.0-rc.1 becomes v1.3.0-rc.2 unless a bigger
+change came in. Without --pre, the release a pre-release leads to is next,
+even without new commits.
+
+--tag creates an annotated tag on HEAD listing the changes, written by the
+chat model with --ai. The tag is not pushed.`,
+	Args: cobra.NoArgs,
+	RunE: runVersionNext,
+}
+
+var (
+	versionPre string
+	versionTag bool
+	versionAI  bool
+)
+
+func init() {
+	versionNextCmd.Flags().StringVar(&versionPre, "pre", "", "Make the next version a pre-release on this channel, e.g. rc or beta")
+	versionNextCmd.Flags().BoolVar(&versionTag, "tag", false, "Create an annotated tag for the next version on HEAD")
+	versionNextCmd.Flags().BoolVar(&versionAI, "ai", false, "Let the chat model write the tag notes (with --tag)")
+	versionCmd.AddCommand(versionNextCmd)
+	rootCmd.AddCommand(versionCmd)
+}
+
+func runVersionNext(cmd *cobra.Command, _ []string) error {
+	if !git.IsGitRepo() {
+		return fmt.Errorf("not a git repository")
+	}
+	if versionAI && !versionTag {
+		return fmt.Errorf("--ai writes the tag notes, use it with --tag")
+	}
+
+	tags, err := git.Tags("HEAD")
+	if err != nil {
+		return err
+	}
+	last, current, found := semver.Latest(tags)
+	if !found {
+		current = semver.Version{V: true}
+	}
+
+	// the changes count from the last release, so a release after its
+	// pre-releases is bumped and described as a whole
+	from, _, released := semver.LatestRelease(tags)
+	rangeSpec := "HEAD"
+	if released {
+		rangeSpec = from + "..HEAD"
+	} else {
+		from = "the first commit"
+	}
+	commits, err := conventional.Log(rangeSpec)
+	if err != nil {
+		return err
+	}
+	if len(commits) == 0 {
+		return fmt.Errorf("no commits since %s", from)
+	}
+	// a pre-release on HEAD can be released, but not followed by another
+	if versionPre != "" && last != "" && last != from && git.IsAncestor("HEAD", last) {
+		return fmt.Errorf("no commits since %s", last)
+	}
+
+	level := conventional.Bump(commits)
+	next := current.Bump(level, versionPre)
+	if _, err := semver.Parse(next.String()); err != nil {
+		return fmt.Errorf("invalid --pre %q: %w", versionPre, err)
+	}
+
+	fmt.Fprintf(os.Stderr, "%d commit(s) since %s, %s change\n", len(commits), from, level)
+	fmt.Println(next)
+
+	if !versionTag {
+		return nil
+	}
+	if git.IsTag(next.String()) {
+		return fmt.Errorf("tag %s already exists", next)
+	}
+	release, err := tagNotes(cmd, next.String(), commits)
+	if err != nil {
+		return err
+	}
+	if err := git.CreateTag(next.String(), "HEAD", next.String()+"\n"+release.Body()); err != nil {
+		return fmt.Errorf("creating tag: %w", err)
+	}
+	fmt.Fprintf(os.Stderr, "Created tag %s, push it with git push origin %s\n", next, next)
+	return nil
+}
+
+// tagNotes lists every commit of the release, written up by the chat model
+// for --ai. If the model fails the commit subjects are used.
+func tagNotes(cmd *cobra.Command, version string, commits []conventional.Commit) (*changelog.Release, error) {
+	if !versionAI {
+		return changelog.NewRelease(version, "", commits, true), nil
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, err
+	}
+	p, err := provider.NewChat(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	spinner := utils.NewSpinner()
+	spinner.Start()
+	release, err := changelog.NewReleaseWithNotes(cmd.Context(), p, version, "", commits, true)
+	spinner.Stop()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "warning: %v, listing commit subjects instead\n", err)
+		return changelog.NewRelease(version, "", commits, true), nil
+	}
+	return release, nil
+}
@@ -18,11 +18,36 @@ type Config struct {
 	GeminiKey string `json:"gemini_key,omitempty"`
 	Provider  string `json:"provider,omitempty"`
 
+	// ChatProvider and EmbedProvider override Provider for one capability,
+	// e.g. Gemini for commit messages with Ollama embeddings.
+	ChatProvider  string `json:"chat_provider,omitempty"`
+	EmbedProvider string `json:"embed_provider,omitempty"`
+
 	OllamaBaseURL    string `json:"ollama_base_url,omitempty"`
 	OllamaChatModel  string `json:"ollama_chat_model,omitempty"`
 	OllamaEmbedModel string `json:"ollama_embed_model,omitempty"`
 
 	DisableUpdateCheck bool `json:"disable_update_check,omitempty"`
+
+	// Embedding vectors are cached under the user cache dir, see `gix cache`.
+	DisableEmbeddingCache bool `json:"disable_embedding_cache,omitempty"`
+	EmbeddingCacheMB      int  `json:"embedding_cache_mb,omitempty"`
+
+	// ProtectedBranches are never rewritten by `gix split <commit>` without
+	// --force. Defaults to main and master.
+	ProtectedBranches []string `json:"protected_branches,omitempty"`
+
+	// CommitArgs are passed to every `git commit` gix commit runs, before
+	// the flags given after "--", e.g. ["--signoff", "-S"].
+	CommitArgs []string `json:"commit_args,omitempty"`
+
+	// CoAuthors maps --co-author aliases to "Name <email>".
+	CoAuthors map[string]string `json:"co_authors,omitempty"`
+
+	// Trailers are added to every message gix commit and gix split write,
+	// e.g. ["Signed-off-by", "Change-Id", "Team: core"]. Signed-off-by and
+	// Change-Id without a value are filled in.
+	Trailers []string `json:"trailers,omitempty"`
 }
 
 // ResolveProvider returns the active provider name, defaulting to "openai".
@@ -33,17 +58,54 @@ func (c Config) ResolveProvider() string {
 	return "openai"
 }
 
+// ResolveChatProvider returns the provider used for commit messages and
+// split plans.
+func (c Config) ResolveChatProvider() string {
+	if c.ChatProvider != "" {
+		return c.ChatProvider
+	}
+	return c.ResolveProvider()
+}
+
+// ResolveEmbedProvider returns the provider used for embeddings.
+func (c Config) ResolveEmbedProvider() string {
+	if c.EmbedProvider != "" {
+		return c.EmbedProvider
+	}
+	return c.ResolveProvider()
+}
+
+// APIKey returns the key of the default provider.
 func (c Config) APIKey() string {
-	switch c.ResolveProvider() {
+	return c.KeyFor(c.ResolveProvider())
+}
+
+// KeyFor returns the API key of the named provider.
+func (c Config) KeyFor(provider string) string {
+	switch provider {
 	case "gemini":
 		return c.GeminiKey
-	case "ollama":
+	case "ollama", "local":
 		return ""
 	default:
 		return c.OpenAIKey
 	}
 }
 
+// EmbeddingCacheBytes returns the configured cache size limit, 0 meaning
+// the cache default.
+func (c Config) EmbeddingCacheBytes() int64 {
+	return int64(c.EmbeddingCacheMB) << 20
+}
+
+// Protected returns the branches history must not be rewritten on.
+func (c Config) Protected() []string {
+	if len(c.ProtectedBranches) > 0 {
+		return c.ProtectedBranches
+	}
+	return []string{"main", "master"}
+}
+
 // Load reads configuration from disk
 func Load() (Config, error) {
 	path, err := configPath()
@@ -56,6 +56,38 @@ func TestConfig_APIKey_DefaultsToOpenAI(t *testing.T) {
 	}
 }
 
+func TestConfig_CapabilityProviders(t *testing.T) {
+	cfg := Config{Provider: "gemini"}
+	if cfg.ResolveChatProvider() != "gemini" || cfg.ResolveEmbedProvider() != "gemini" {
+		t.Errorf("expected both capabilities to default to the provider, got %q and %q",
+			cfg.ResolveChatProvider(), cfg.ResolveEmbedProvider())
+	}
+
+	cfg.EmbedProvider = "ollama"
+	if cfg.ResolveChatProvider() != "gemini" || cfg.ResolveEmbedProvider() != "ollama" {
+		t.Errorf("expected gemini chat with ollama embeddings, got %q and %q",
+			cfg.ResolveChatProvider(), cfg.ResolveEmbedProvider())
+	}
+}
+
+func TestConfig_KeyFor(t *testing.T) {
+	cfg := Config{OpenAIKey: "sk", GeminiKey: "g"}
+	if cfg.KeyFor("gemini") != "g" || cfg.KeyFor("openai") != "sk" || cfg.KeyFor("ollama") != "" {
+		t.Errorf("unexpected keys: %q %q %q", cfg.KeyFor("gemini"), cfg.KeyFor("openai"), cfg.KeyFor("ollama"))
+	}
+}
+
+func TestConfig_Protected(t *testing.T) {
+	if got := (Config{}).Protected(); len(got) != 2 || got[0] != "main" || got[1] != "master" {
+		t.Errorf("expected default protected branches [main master], got %v", got)
+	}
+
+	cfg := Config{ProtectedBranches: []string{"release"}}
+	if got := cfg.Protected(); len(got) != 1 || got[0] != "release" {
+		t.Errorf("expected configured protected branches, got %v", got)
+	}
+}
+
 func TestSaveAndLoad(t *testing.T) {
 	setTestHome(t)
 
@@ -0,0 +1,103 @@
+// Package conventional parses Conventional Commits messages, see
+// https://www.conventionalcommits.org/en/v1.0.0/.
+package conventional
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/ademajagon/gix/internal/git"
+	"github.com/ademajagon/gix/semver"
+)
+
+// Commit is a parsed commit message. Type is empty when the subject does
+// not follow the convention, Subject is then the whole first line.
+type Commit struct {
+	Hash     string
+	Type     string
+	Scope    string
+	Subject  string
+	Body     string
+	Breaking bool
+	// BreakingNote is the text of a BREAKING CHANGE footer, if any.
+	BreakingNote string
+}
+
+var headerPattern = regexp.MustCompile(`^([A-Za-z]+)(?:\(([^()]*)\))?(!)?: +(.+)$`)
+
+// Parse parses message, the full message of the commit hash.
+func Parse(hash, message string) Commit {
+	message = strings.TrimSpace(strings.ReplaceAll(message, "\r\n", "\n"))
+	header, body, _ := strings.Cut(message, "\n")
+	c := Commit{Hash: hash, Subject: strings.TrimSpace(header), Body: strings.TrimSpace(body)}
+
+	if m := headerPattern.FindStringSubmatch(c.Subject); m != nil {
+		c.Type = strings.ToLower(m[1])
+		c.Scope = strings.TrimSpace(m[2])
+		c.Breaking = m[3] == "!"
+		c.Subject = strings.TrimSpace(m[4])
+	}
+
+	if note, ok := breakingNote(c.Body); ok {
+		c.Breaking = true
+		c.BreakingNote = note
+	}
+	return c
+}
+
+// breakingNote returns the text of a BREAKING CHANGE footer in body,
+// including the lines that continue it up to the next footer.
+func breakingNote(body string) (string, bool) {
+	lines := strings.Split(body, "\n")
+	for i, line := range lines {
+		var note string
+		var ok bool
+		for _, key := range []string{"BREAKING CHANGE:", "BREAKING-CHANGE:"} {
+			if rest, found := strings.CutPrefix(line, key); found {
+				note, ok = strings.TrimSpace(rest), true
+			}
+		}
+		if !ok {
+			continue
+		}
+		for _, next := range lines[i+1:] {
+			if next == "" || footerPattern.MatchString(next) {
+				break
+			}
+			note += " " + strings.TrimSpace(next)
+		}
+		return strings.TrimSpace(note), true
+	}
+	return "", false
+}
+
+var footerPattern = regexp.MustCompile(`^[A-Za-z-]+(: | #)`)
+
+// Bump returns the version change commits call for: major when one of them
+// is breaking, minor when one adds a feature, patch otherwise.
+func Bump(commits []Commit) semver.Level {
+	level := semver.Patch
+	for _, c := range commits {
+		switch {
+		case c.Breaking:
+			return semver.Major
+		case c.Type == "feat":
+			level = semver.Minor
+		}
+	}
+	return level
+}
+
+// Log returns the parsed commits selected by args, e.g. "v1.0.0..HEAD",
+// newest first. Merge commits are left out.
+func Log(args ...string) ([]Commit, error) {
+	logged, err := git.Log(append([]string{"--no-merges"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	commits := make([]Commit, len(logged))
+	for i, l := range logged {
+		commits[i] = Parse(l.Hash, l.Message)
+	}
+	return commits, nil
+}
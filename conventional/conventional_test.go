@@ -0,0 +1,76 @@
+package conventional
+
+import (
+	"testing"
+
+	"github.com/ademajagon/gix/semver"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name    string
+		message string
+		want    Commit
+	}{
+		{
+			name:    "type and scope",
+			message: "feat(provider): add ollama support\n\nRuns locally.",
+			want:    Commit{Type: "feat", Scope: "provider", Subject: "add ollama support", Body: "Runs locally."},
+		},
+		{
+			name:    "no scope",
+			message: "fix: handle empty diff",
+			want:    Commit{Type: "fix", Subject: "handle empty diff"},
+		},
+		{
+			name:    "bang",
+			message: "refactor(config)!: rename keys",
+			want:    Commit{Type: "refactor", Scope: "config", Subject: "rename keys", Breaking: true},
+		},
+		{
+			name:    "breaking footer",
+			message: "Feat: drop v1 api\n\nCleanup.\n\nBREAKING CHANGE: clients must\nmove to /v2.\nRefs: #12",
+			want: Commit{
+				Type: "feat", Subject: "drop v1 api", Body: "Cleanup.\n\nBREAKING CHANGE: clients must\nmove to /v2.\nRefs: #12",
+				Breaking: true, BreakingNote: "clients must move to /v2.",
+			},
+		},
+		{
+			name:    "not conventional",
+			message: "Update README.md",
+			want:    Commit{Subject: "Update README.md"},
+		},
+		{
+			name:    "merge style subject",
+			message: "Merge branch 'main': sync",
+			want:    Commit{Subject: "Merge branch 'main': sync"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Parse("", tt.message); got != tt.want {
+				t.Errorf("got  %+v\nwant %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBump(t *testing.T) {
+	fix := Commit{Type: "fix"}
+	feat := Commit{Type: "feat"}
+	breaking := Commit{Type: "refactor", Breaking: true}
+
+	tests := []struct {
+		commits []Commit
+		want    semver.Level
+	}{
+		{[]Commit{fix, {Subject: "Update README"}}, semver.Patch},
+		{[]Commit{fix, feat}, semver.Minor},
+		{[]Commit{feat, breaking, fix}, semver.Major},
+	}
+	for _, tt := range tests {
+		if got := Bump(tt.commits); got != tt.want {
+			t.Errorf("Bump(%+v) = %s, want %s", tt.commits, got, tt.want)
+		}
+	}
+}
@@ -2,7 +2,9 @@ package git
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
+	"os"
 	"os/exec"
 	"strings"
 )
@@ -19,28 +21,105 @@ func IsGitRepo() bool {
 
 // HasStagedChanges checks whether there are any staged changes.
 func HasStagedChanges() (bool, error) {
-	out, err := exec.Command("git", "diff", "--cached", "--name-only").Output()
+	return Index{}.HasChanges()
+}
+
+func GetStagedDiff(maxBytes int) (string, error) {
+	return Index{}.StagedDiff(maxBytes)
+}
+
+// GetAmendDiff returns what `git commit --amend` would commit: the changes
+// of HEAD together with the staged ones, against HEAD's parent.
+func GetAmendDiff(maxBytes int) (string, error) {
+	return Index{}.AmendDiff(maxBytes)
+}
+
+// HasChanges reports whether the index has changes staged against HEAD.
+func (ix Index) HasChanges() (bool, error) {
+	out, err := ix.run("", "diff", "--cached", "--name-only")
 	if err != nil {
-		return false, fmt.Errorf("git diff --cached --name-only: %w", err)
+		return false, err
 	}
+	return out != "", nil
+}
 
-	return strings.TrimSpace(string(out)) != "", nil
+// StagedDiff returns the changes staged in the index against HEAD.
+func (ix Index) StagedDiff(maxBytes int) (string, error) {
+	diff, err := getDiff(ix.env(), maxBytes, "diff", "--cached", "--unified=3")
+	if errors.Is(err, errNoChanges) {
+		return "", fmt.Errorf("no staged changes - `git add <files>` to stage changes")
+	}
+	return diff, err
 }
 
-func GetStagedDiff(maxBytes int) (string, error) {
+// AmendDiff returns what amending HEAD with the index would commit, the
+// changes against HEAD's parent.
+func (ix Index) AmendDiff(maxBytes int) (string, error) {
+	from, err := parentOrEmptyTree("HEAD")
+	if err != nil {
+		return "", err
+	}
+	return getDiff(ix.env(), maxBytes, "diff", "--cached", "--unified=3", from)
+}
+
+// GetCommitDiff returns the changes commit made to its parent.
+func GetCommitDiff(commit string, maxBytes int) (string, error) {
+	from, err := parentOrEmptyTree(commit)
+	if err != nil {
+		return "", err
+	}
+	return getDiff(nil, maxBytes, "diff", "--unified=3", from, commit)
+}
+
+// GetRangeDiff returns the changes between the commits from and to.
+func GetRangeDiff(from, to string, maxBytes int) (string, error) {
+	return getDiff(nil, maxBytes, "diff", "--unified=3", from, to)
+}
+
+// DiffTrees returns the complete diff from one tree or commit to another,
+// never truncated, as a patch `git apply` and `git am` accept.
+func DiffTrees(from, to string) (string, error) {
+	var buf bytes.Buffer
+	cmd := exec.Command("git", "diff", "--unified=3", from, to)
+	cmd.Stdout = &buf
+
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git diff %s %s: %w", from, to, err)
+	}
+	return buf.String(), nil
+}
+
+// parentOrEmptyTree returns commit's first parent, or the empty tree for a
+// root commit, so diffs against it show every file as added.
+func parentOrEmptyTree(commit string) (string, error) {
+	parents, err := Parents(commit)
+	if err != nil {
+		return "", err
+	}
+	if len(parents) > 0 {
+		return parents[0], nil
+	}
+	return EmptyTree()
+}
+
+var errNoChanges = errors.New("no changes to describe")
+
+func getDiff(env []string, maxBytes int, args ...string) (string, error) {
 	var buf bytes.Buffer
-	cmd := exec.Command("git", "diff", "--cached", "--unified=3")
+	cmd := exec.Command("git", args...)
 	cmd.Stdout = &buf
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
 
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("git diff --cached: %w", err)
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
 	}
 
 	diff := strings.TrimSpace(buf.String())
 	if diff == "" {
-		return "", fmt.Errorf("no staged changes - `git add <files>` to stage changes")
+		return "", errNoChanges
 	}
-
 	return truncateDiff(diff, maxBytes), nil
 }
 
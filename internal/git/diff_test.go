@@ -1,6 +1,7 @@
 package git
 
 import (
+	"os"
 	"strings"
 	"testing"
 )
@@ -125,3 +126,46 @@ func TestCountFiles(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAmendDiff(t *testing.T) {
+	newRepo(t)
+	commitFile(t, "a.txt", "a\n", "root")
+	commitFile(t, "b.txt", "b\n", "second")
+	if err := os.WriteFile("c.txt", []byte("c\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run(t, "add", "c.txt")
+	if err := os.WriteFile("d.txt", []byte("unstaged\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	diff, err := GetAmendDiff(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if countFiles(diff) != 2 || !strings.Contains(diff, "+++ b/b.txt") || !strings.Contains(diff, "+++ b/c.txt") {
+		t.Errorf("expected HEAD's and the staged changes, got:\n%s", diff)
+	}
+}
+
+func TestGetCommitDiff_RootCommit(t *testing.T) {
+	newRepo(t)
+	root := commitFile(t, "a.txt", "a\n", "root")
+	commitFile(t, "b.txt", "b\n", "second")
+
+	diff, err := GetCommitDiff(root, 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if countFiles(diff) != 1 || !strings.Contains(diff, "+++ b/a.txt\n@@ -0,0 +1 @@\n+a") {
+		t.Errorf("expected the root commit to add a.txt, got:\n%s", diff)
+	}
+
+	diff, err = GetCommitDiff("HEAD", 1<<20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if countFiles(diff) != 1 || !strings.Contains(diff, "+++ b/b.txt") {
+		t.Errorf("expected HEAD to add b.txt, got:\n%s", diff)
+	}
+}
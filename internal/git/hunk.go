@@ -5,19 +5,39 @@ import (
 	"bytes"
 	"fmt"
 	"os/exec"
+	"strconv"
 	"strings"
 )
 
 // Hunk represents a single diff hunk within a file.
 // Used by `gix split` (beta).
 type Hunk struct {
-	FilePath string
-	Header   string
-	Body     string
+	FilePath string `json:"file_path"`
+	Header   string `json:"header"`
+	Body     string `json:"body"`
+
+	// FileHeader holds the "diff --git", "index", "---" and "+++" lines
+	// that precede the hunk, Body starts with it.
+	FileHeader string `json:"file_header"`
+
+	// Line ranges parsed from Header. Counts are 0 for pure insertions
+	// (old side) or pure deletions (new side).
+	OldStart int `json:"old_start"`
+	OldLines int `json:"old_lines"`
+	NewStart int `json:"new_start"`
+	NewLines int `json:"new_lines"`
 }
 
+// ParseHunks parses the staged diff with git's default three lines of context.
 func ParseHunks(maxBytes int) ([]Hunk, error) {
-	cmd := exec.Command("git", "diff", "--cached", "--unified=3")
+	return ParseHunksUnified(maxBytes, 3)
+}
+
+// ParseHunksUnified parses the staged diff generated with the given number
+// of context lines. unified=0 yields one hunk per contiguous change block,
+// which must then be applied with `git apply --unidiff-zero`.
+func ParseHunksUnified(maxBytes, unified int) ([]Hunk, error) {
+	cmd := exec.Command("git", "diff", "--cached", fmt.Sprintf("--unified=%d", unified))
 	var buf bytes.Buffer
 	cmd.Stdout = &buf
 
@@ -28,6 +48,32 @@ func ParseHunks(maxBytes int) ([]Hunk, error) {
 	return parseHunksFromDiff(truncateDiff(buf.String(), maxBytes))
 }
 
+// ParseRevisionHunks parses the diff between two commits, from "" meaning
+// the empty tree. Unlike the staged diff it is never truncated: hunks that
+// are cut off could not be put back into history, so a diff larger than
+// maxBytes is an error.
+func ParseRevisionHunks(from, to string, maxBytes, unified int) ([]Hunk, error) {
+	if from == "" {
+		var err error
+		if from, err = EmptyTree(); err != nil {
+			return nil, err
+		}
+	}
+
+	cmd := exec.Command("git", "diff", fmt.Sprintf("--unified=%d", unified), from, to)
+	var buf bytes.Buffer
+	cmd.Stdout = &buf
+
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git diff %s %s: %w", from, to, err)
+	}
+	if buf.Len() > maxBytes {
+		return nil, fmt.Errorf("diff is too large to split (%d bytes, limit %d)", buf.Len(), maxBytes)
+	}
+
+	return parseHunksFromDiff(buf.String())
+}
+
 // parseHunksFromDiff parses raw diff text into hunks.
 func parseHunksFromDiff(diff string) ([]Hunk, error) {
 	scanner := bufio.NewScanner(strings.NewReader(diff))
@@ -40,18 +86,23 @@ func parseHunksFromDiff(diff string) ([]Hunk, error) {
 	var hunkLines []string
 	var hunkHeader string
 
-	flush := func() {
+	flush := func() error {
 		if hunkHeader == "" || len(hunkLines) == 0 {
-			return
+			return nil
+		}
+		h := Hunk{
+			FilePath:   currentFile,
+			Header:     hunkHeader,
+			Body:       strings.Join(append(append([]string(nil), fileHeader...), hunkLines...), "\n"),
+			FileHeader: strings.Join(fileHeader, "\n"),
 		}
-		body := strings.Join(append(fileHeader, hunkLines...), "\n")
-		hunks = append(hunks, Hunk{
-			FilePath: currentFile,
-			Header:   hunkHeader,
-			Body:     body,
-		})
+		if err := h.parseRanges(); err != nil {
+			return err
+		}
+		hunks = append(hunks, h)
 		hunkLines = nil
 		hunkHeader = ""
+		return nil
 	}
 
 	for scanner.Scan() {
@@ -59,22 +110,26 @@ func parseHunksFromDiff(diff string) ([]Hunk, error) {
 
 		switch {
 		case strings.HasPrefix(line, "diff --git "):
-			flush()
+			if err := flush(); err != nil {
+				return nil, err
+			}
 			currentFile = parseFilePath(line)
 			fileHeader = []string{line}
 			hunkHeader = ""
 
-		case strings.HasPrefix(line, "index ") ||
+		case hunkHeader == "" && (strings.HasPrefix(line, "index ") ||
 			strings.HasPrefix(line, "--- ") ||
 			strings.HasPrefix(line, "+++ ") ||
 			strings.HasPrefix(line, "new file") ||
 			strings.HasPrefix(line, "deleted file") ||
 			strings.HasPrefix(line, "old mode") ||
-			strings.HasPrefix(line, "new mode"):
+			strings.HasPrefix(line, "new mode")):
 			fileHeader = append(fileHeader, line)
 
 		case strings.HasPrefix(line, "@@ "):
-			flush()
+			if err := flush(); err != nil {
+				return nil, err
+			}
 			hunkHeader = line
 			hunkLines = []string{line}
 
@@ -85,7 +140,9 @@ func parseHunksFromDiff(diff string) ([]Hunk, error) {
 		}
 	}
 
-	flush()
+	if err := flush(); err != nil {
+		return nil, err
+	}
 
 	if err := scanner.Err(); err != nil {
 		return nil, fmt.Errorf("scanning diff output: %w", err)
@@ -102,3 +159,180 @@ func parseFilePath(diffLine string) string {
 	right := parts[3] // "b/foo/bar.go"
 	return strings.TrimPrefix(right, "b/")
 }
+
+// parseRanges fills the line ranges from the "@@ -a,b +c,d @@" header.
+func (h *Hunk) parseRanges() error {
+	fields := strings.Fields(h.Header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[1], "-") || !strings.HasPrefix(fields[2], "+") {
+		return fmt.Errorf("malformed hunk header %q", h.Header)
+	}
+
+	var err error
+	if h.OldStart, h.OldLines, err = parseRange(fields[1][1:]); err != nil {
+		return fmt.Errorf("malformed hunk header %q: %w", h.Header, err)
+	}
+	if h.NewStart, h.NewLines, err = parseRange(fields[2][1:]); err != nil {
+		return fmt.Errorf("malformed hunk header %q: %w", h.Header, err)
+	}
+	return nil
+}
+
+// parseRange parses "start,count" where a missing count means 1.
+func parseRange(s string) (start, count int, err error) {
+	startStr, countStr, hasCount := strings.Cut(s, ",")
+	if start, err = strconv.Atoi(startStr); err != nil {
+		return 0, 0, err
+	}
+	if !hasCount {
+		return start, 1, nil
+	}
+	if count, err = strconv.Atoi(countStr); err != nil {
+		return 0, 0, err
+	}
+	return start, count, nil
+}
+
+// Lines returns the hunk's content lines, without the file and hunk headers.
+func (h Hunk) Lines() []string {
+	body := h.Body
+	if h.FileHeader != "" {
+		body = strings.TrimPrefix(body, h.FileHeader+"\n")
+	}
+	body = strings.TrimPrefix(body, h.Header)
+	body = strings.TrimPrefix(body, "\n")
+	if body == "" {
+		return nil
+	}
+	return strings.Split(body, "\n")
+}
+
+// Delta is the number of lines the hunk adds to the file (negative if it
+// removes more than it adds).
+func (h Hunk) Delta() int {
+	return h.NewLines - h.OldLines
+}
+
+// HasContext reports whether the hunk carries any unchanged context lines.
+// Hunks without context must be applied with `git apply --unidiff-zero`.
+func (h Hunk) HasContext() bool {
+	for _, l := range h.Lines() {
+		if strings.HasPrefix(l, " ") {
+			return true
+		}
+	}
+	return false
+}
+
+// WithStart returns a copy of the hunk moved to a new position in the old
+// file, with the new-side start recomputed as if the hunk were applied on
+// its own. Used to re-target hunks when only some of them are applied.
+func (h Hunk) WithStart(oldStart int) Hunk {
+	newStart := oldStart
+	switch {
+	case h.OldLines == 0:
+		newStart = oldStart + 1 // pure insertion: old start is the line before
+	case h.NewLines == 0:
+		newStart = oldStart - 1 // pure deletion: new start is the line before
+	}
+	return h.rebuild(oldStart, h.OldLines, newStart, h.NewLines, h.Lines())
+}
+
+func (h Hunk) rebuild(oldStart, oldLines, newStart, newLines int, lines []string) Hunk {
+	header := fmt.Sprintf("@@ -%d,%d +%d,%d @@", oldStart, oldLines, newStart, newLines)
+	if _, section, ok := strings.Cut(strings.TrimPrefix(h.Header, "@@ "), " @@"); ok && section != "" {
+		header += section
+	}
+
+	parts := make([]string, 0, len(lines)+2)
+	if h.FileHeader != "" {
+		parts = append(parts, h.FileHeader)
+	}
+	parts = append(parts, header)
+	parts = append(parts, lines...)
+
+	h.Header = header
+	h.Body = strings.Join(parts, "\n")
+	h.OldStart, h.OldLines = oldStart, oldLines
+	h.NewStart, h.NewLines = newStart, newLines
+	return h
+}
+
+// SplitHunks breaks every hunk into minimal change blocks, see SplitHunk.
+func SplitHunks(hunks []Hunk) []Hunk {
+	var out []Hunk
+	for _, h := range hunks {
+		out = append(out, SplitHunk(h)...)
+	}
+	return out
+}
+
+// SplitHunk breaks a hunk into one zero-context hunk per contiguous run of
+// added/removed lines, recomputing each header. `git diff --unified=3` often
+// merges unrelated edits into one hunk; splitting lets them land in
+// different commits. The results must be applied with --unidiff-zero.
+func SplitHunk(h Hunk) []Hunk {
+	lines := h.Lines()
+
+	var out []Hunk
+	var block []string
+	oldLine, newLine := h.OldStart, h.NewStart
+	var blockOld, blockNew, oldCount, newCount int
+
+	// Zero-count sides point at the line before the change, step past it so
+	// oldLine/newLine always name the next line to be consumed.
+	if h.OldLines == 0 {
+		oldLine++
+	}
+	if h.NewLines == 0 {
+		newLine++
+	}
+
+	flush := func() {
+		if oldCount == 0 && newCount == 0 {
+			block = nil
+			return
+		}
+		oldStart, newStart := blockOld, blockNew
+		if oldCount == 0 {
+			oldStart-- // insertion after the previous line
+		}
+		if newCount == 0 {
+			newStart--
+		}
+		out = append(out, h.rebuild(oldStart, oldCount, newStart, newCount, block))
+		block = nil
+		oldCount, newCount = 0, 0
+	}
+
+	for _, l := range lines {
+		switch {
+		case strings.HasPrefix(l, "-"), strings.HasPrefix(l, "+"):
+			if oldCount == 0 && newCount == 0 {
+				blockOld, blockNew = oldLine, newLine
+			}
+			block = append(block, l)
+			if l[0] == '-' {
+				oldCount++
+				oldLine++
+			} else {
+				newCount++
+				newLine++
+			}
+		case strings.HasPrefix(l, `\`):
+			// "\ No newline at end of file" belongs to the preceding line.
+			if len(block) > 0 {
+				block = append(block, l)
+			}
+		default:
+			flush()
+			oldLine++
+			newLine++
+		}
+	}
+	flush()
+
+	if len(out) == 0 {
+		return []Hunk{h}
+	}
+	return out
+}
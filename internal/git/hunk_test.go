@@ -0,0 +1,164 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+const fileHeader = "diff --git a/f.go b/f.go\nindex 0ff3bbb..3c63530 100644\n--- a/f.go\n+++ b/f.go"
+
+func TestParseHunksFromDiff_Ranges(t *testing.T) {
+	diff := fileHeader + "\n@@ -3 +3 @@\n-3\n+three\n@@ -12,0 +13,2 @@ func foo()\n+a\n+b\n"
+
+	hunks, err := parseHunksFromDiff(diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(hunks))
+	}
+
+	h := hunks[0]
+	if h.OldStart != 3 || h.OldLines != 1 || h.NewStart != 3 || h.NewLines != 1 {
+		t.Errorf("unexpected ranges for first hunk: %+v", h)
+	}
+	if h.FileHeader != fileHeader {
+		t.Errorf("unexpected file header: %q", h.FileHeader)
+	}
+
+	h = hunks[1]
+	if h.OldStart != 12 || h.OldLines != 0 || h.NewStart != 13 || h.NewLines != 2 {
+		t.Errorf("unexpected ranges for second hunk: %+v", h)
+	}
+	if got := h.Lines(); len(got) != 2 || got[0] != "+a" {
+		t.Errorf("unexpected lines: %q", got)
+	}
+}
+
+func TestParseHunksFromDiff_RemovedLineLooksLikeHeader(t *testing.T) {
+	diff := fileHeader + "\n@@ -1,2 +1,1 @@\n--- old comment\n keep\n"
+
+	hunks, err := parseHunksFromDiff(diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(hunks) != 1 {
+		t.Fatalf("expected 1 hunk, got %d", len(hunks))
+	}
+	if !strings.Contains(hunks[0].Body, "\n--- old comment\n") {
+		t.Errorf("removed line was not kept in the body: %q", hunks[0].Body)
+	}
+}
+
+func TestParseHunksFromDiff_MalformedHeader(t *testing.T) {
+	_, err := parseHunksFromDiff(fileHeader + "\n@@ garbage @@\n+x\n")
+	if err == nil {
+		t.Fatal("expected error for malformed hunk header, got nil")
+	}
+}
+
+func TestSplitHunk_SeparatesChangeBlocks(t *testing.T) {
+	diff := fileHeader + "\n@@ -1,15 +1,16 @@\n 1\n 2\n-3\n+three\n 4\n-5\n+five\n 6\n 7\n 8\n 9\n 10\n 11\n-12\n+twelve\n+extra\n 13\n 14\n 15\n"
+	hunks, err := parseHunksFromDiff(diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	blocks := SplitHunk(hunks[0])
+	if len(blocks) != 3 {
+		t.Fatalf("expected 3 blocks, got %d", len(blocks))
+	}
+
+	want := []string{
+		"@@ -3,1 +3,1 @@",
+		"@@ -5,1 +5,1 @@",
+		"@@ -12,1 +12,2 @@",
+	}
+	for i, b := range blocks {
+		if b.Header != want[i] {
+			t.Errorf("block %d: header %q, want %q", i, b.Header, want[i])
+		}
+		if !strings.HasPrefix(b.Body, fileHeader+"\n"+b.Header+"\n") {
+			t.Errorf("block %d: body does not start with headers: %q", i, b.Body)
+		}
+		if b.HasContext() {
+			t.Errorf("block %d: expected no context lines", i)
+		}
+	}
+}
+
+func TestSplitHunk_PureInsertionAndDeletion(t *testing.T) {
+	diff := fileHeader + "\n@@ -4,7 +4,7 @@ func main() {\n 4\n 5\n+new\n 6\n 7\n-8\n 9\n 10\n"
+	hunks, err := parseHunksFromDiff(diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	blocks := SplitHunk(hunks[0])
+	if len(blocks) != 2 {
+		t.Fatalf("expected 2 blocks, got %d", len(blocks))
+	}
+	if blocks[0].Header != "@@ -5,0 +6,1 @@ func main() {" {
+		t.Errorf("unexpected insertion header %q", blocks[0].Header)
+	}
+	if blocks[1].Header != "@@ -8,1 +8,0 @@ func main() {" {
+		t.Errorf("unexpected deletion header %q", blocks[1].Header)
+	}
+}
+
+func TestSplitHunk_ZeroContextInsertionUnchanged(t *testing.T) {
+	hunks, err := parseHunksFromDiff(fileHeader + "\n@@ -12,0 +13,2 @@\n+a\n+b\n")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	blocks := SplitHunk(hunks[0])
+	if len(blocks) != 1 {
+		t.Fatalf("expected 1 block, got %d", len(blocks))
+	}
+	if blocks[0].OldStart != 12 || blocks[0].NewStart != 13 {
+		t.Errorf("unexpected ranges: %+v", blocks[0])
+	}
+}
+
+func TestSplitHunk_KeepsNoNewlineMarker(t *testing.T) {
+	diff := fileHeader + "\n@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+c\n\\ No newline at end of file\n"
+	hunks, err := parseHunksFromDiff(diff)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	blocks := SplitHunk(hunks[0])
+	if len(blocks) != 1 {
+		t.Fatalf("expected 1 block, got %d", len(blocks))
+	}
+	if got := strings.Count(blocks[0].Body, "No newline"); got != 2 {
+		t.Errorf("expected both no-newline markers, got %d in %q", got, blocks[0].Body)
+	}
+}
+
+func TestHunk_WithStart(t *testing.T) {
+	cases := []struct {
+		header string
+		start  int
+		want   string
+	}{
+		{"@@ -10,3 +12,4 @@", 7, "@@ -7,3 +7,4 @@"},
+		{"@@ -10,0 +12,2 @@", 7, "@@ -7,0 +8,2 @@"},
+		{"@@ -10,2 +8,0 @@", 7, "@@ -7,2 +6,0 @@"},
+	}
+
+	for _, c := range cases {
+		hunks, err := parseHunksFromDiff(fileHeader + "\n" + c.header + "\n+x\n")
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		got := hunks[0].WithStart(c.start)
+		if got.Header != c.want {
+			t.Errorf("WithStart(%d) on %q = %q, want %q", c.start, c.header, got.Header, c.want)
+		}
+		if got.Delta() != hunks[0].Delta() {
+			t.Errorf("WithStart changed delta for %q", c.header)
+		}
+	}
+}
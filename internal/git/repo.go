@@ -0,0 +1,441 @@
+package git
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Dir returns the absolute path of the repository's .git directory.
+func Dir() (string, error) {
+	return output("rev-parse", "--absolute-git-dir")
+}
+
+// TopLevel returns the absolute path of the working tree's root.
+func TopLevel() (string, error) {
+	return output("rev-parse", "--show-toplevel")
+}
+
+// ResolveRef returns the full object name of rev.
+func ResolveRef(rev string) (string, error) {
+	return output("rev-parse", "--verify", "--quiet", rev+"^{commit}")
+}
+
+// WriteTree writes the current index as a tree object and returns its name.
+func WriteTree() (string, error) {
+	return output("write-tree")
+}
+
+// Var returns a git logical variable such as GIT_AUTHOR_IDENT.
+func Var(name string) (string, error) {
+	return output("var", name)
+}
+
+// ReadTree replaces the current index with tree, leaving the working tree
+// alone, and refreshes the stat information so unchanged files stay clean.
+func ReadTree(tree string) error {
+	if _, err := output("read-tree", tree); err != nil {
+		return err
+	}
+	_, _ = output("update-index", "-q", "--refresh")
+	return nil
+}
+
+// CommitTree creates a commit object for tree without touching any ref.
+// parent may be empty for a root commit.
+func CommitTree(tree, parent, message string) (string, error) {
+	args := []string{"commit-tree", tree}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	return command(nil, message, append(args, "-F", "-")...)
+}
+
+// Message returns the full commit message of commit.
+func Message(commit string) (string, error) {
+	return output("log", "-1", "--format=%B", commit)
+}
+
+// CopyCommit creates a commit with the tree and author of commit on top of
+// parent, like a rebase replaying it, with message as its new message.
+func CopyCommit(commit, parent, message string) (string, error) {
+	return CommitTreeAs(commit+"^{tree}", parent, commit, message)
+}
+
+// CommitTreeAs is CommitTree with the author name, email and date of the
+// existing commit author, as rebase keeps them. An empty author falls back
+// to CommitTree.
+func CommitTreeAs(tree, parent, author, message string) (string, error) {
+	if author == "" {
+		return CommitTree(tree, parent, message)
+	}
+	ident, err := output("log", "-1", "--date=raw", "--format=%an%x00%ae%x00%ad", author)
+	if err != nil {
+		return "", err
+	}
+	fields := strings.Split(ident, "\x00")
+	if len(fields) != 3 {
+		return "", fmt.Errorf("reading author of %s", author)
+	}
+
+	args := []string{"commit-tree", tree}
+	if parent != "" {
+		args = append(args, "-p", parent)
+	}
+	env := []string{
+		"GIT_AUTHOR_NAME=" + fields[0],
+		"GIT_AUTHOR_EMAIL=" + fields[1],
+		"GIT_AUTHOR_DATE=" + fields[2],
+	}
+	return command(env, message, append(args, "-F", "-")...)
+}
+
+// Authors returns the distinct authors of all commits as "Name <email>",
+// those with the most commits first.
+func Authors() ([]string, error) {
+	out, err := output("log", "--all", "--format=%aN <%aE>")
+	if err != nil || out == "" {
+		return nil, err
+	}
+
+	counts := make(map[string]int)
+	var authors []string
+	for _, a := range strings.Split(out, "\n") {
+		if counts[a] == 0 {
+			authors = append(authors, a)
+		}
+		counts[a]++
+	}
+	sort.SliceStable(authors, func(i, j int) bool {
+		return counts[authors[i]] > counts[authors[j]]
+	})
+	return authors, nil
+}
+
+// InterpretTrailers runs `git interpret-trailers` with args on message, as
+// with --trailer to add trailers or --parse to list them.
+func InterpretTrailers(message string, args ...string) (string, error) {
+	return command(nil, message+"\n", append([]string{"interpret-trailers"}, args...)...)
+}
+
+// UpdateRef atomically moves ref from oldValue to newValue, failing if ref
+// no longer points at oldValue. An empty oldValue requires that ref does
+// not exist yet, an empty newValue deletes it.
+func UpdateRef(ref, newValue, oldValue, reason string) error {
+	if newValue == "" {
+		_, err := output("update-ref", "-m", reason, "-d", ref, oldValue)
+		return err
+	}
+	_, err := output("update-ref", "-m", reason, ref, newValue, oldValue)
+	return err
+}
+
+// TreeOf returns the tree object of rev.
+func TreeOf(rev string) (string, error) {
+	return output("rev-parse", "--verify", "--quiet", rev+"^{tree}")
+}
+
+// EmptyTree returns the name of the empty tree in this repository's hash.
+func EmptyTree() (string, error) {
+	return output("hash-object", "-t", "tree", "--stdin")
+}
+
+// MergeBase returns the best common ancestor of a and b.
+func MergeBase(a, b string) (string, error) {
+	return output("merge-base", a, b)
+}
+
+// Upstream returns the upstream of branch, e.g. "origin/main", or "" when
+// it has none.
+func Upstream(branch string) string {
+	out, err := output("rev-parse", "--abbrev-ref", "--symbolic-full-name", branch+"@{upstream}")
+	if err != nil {
+		return ""
+	}
+	return out
+}
+
+// RemoteHead returns the default branch of remote, e.g. "origin/main", or
+// "" when it is not known locally.
+func RemoteHead(remote string) string {
+	out, err := output("rev-parse", "--abbrev-ref", remote+"/HEAD")
+	if err != nil || out == remote+"/HEAD" {
+		return ""
+	}
+	return out
+}
+
+// IsAncestor reports whether ancestor is reachable from descendant. A commit
+// is its own ancestor.
+func IsAncestor(ancestor, descendant string) bool {
+	_, err := output("merge-base", "--is-ancestor", ancestor, descendant)
+	return err == nil
+}
+
+// LoggedCommit is a commit as listed by Log.
+type LoggedCommit struct {
+	Hash    string
+	Message string
+}
+
+// Log returns the hash and full message of the commits selected by args,
+// newest first.
+func Log(args ...string) ([]LoggedCommit, error) {
+	out, err := output(append([]string{"log", "--format=%H%x00%B%x1e"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	var commits []LoggedCommit
+	for _, record := range strings.Split(out, "\x1e") {
+		hash, message, ok := strings.Cut(strings.TrimLeft(record, "\n"), "\x00")
+		if !ok {
+			continue
+		}
+		commits = append(commits, LoggedCommit{Hash: hash, Message: strings.TrimSpace(message)})
+	}
+	return commits, nil
+}
+
+// CommitDate returns the committer date of rev as YYYY-MM-DD.
+func CommitDate(rev string) (string, error) {
+	return output("log", "-1", "--format=%cs", rev)
+}
+
+// IsTag reports whether name is a tag.
+func IsTag(name string) bool {
+	_, err := output("rev-parse", "--verify", "--quiet", "refs/tags/"+name)
+	return err == nil
+}
+
+// Tags returns the tags reachable from rev.
+func Tags(rev string) ([]string, error) {
+	out, err := output("tag", "--merged", rev)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// CreateTag creates the annotated tag name on rev with message, kept as is
+// so Markdown headings survive.
+func CreateTag(name, rev, message string) error {
+	_, err := command(nil, message, "tag", "--annotate", "--cleanup=whitespace", "--file", "-", name, rev)
+	return err
+}
+
+// LastTag returns the most recent tag reachable from rev, or "" when there
+// is none.
+func LastTag(rev string) string {
+	tag, err := output("describe", "--tags", "--abbrev=0", rev)
+	if err != nil {
+		return ""
+	}
+	return tag
+}
+
+// RevList returns the commits selected by args, newest first.
+func RevList(args ...string) ([]string, error) {
+	out, err := output(append([]string{"rev-list"}, args...)...)
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// Parents returns the parents of commit, none for a root commit.
+func Parents(commit string) ([]string, error) {
+	out, err := output("rev-list", "--parents", "-n", "1", commit)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(out)[1:], nil
+}
+
+// CurrentBranch returns the short name of the checked out branch, or "" when
+// HEAD is detached.
+func CurrentBranch() string {
+	branch, _ := output("symbolic-ref", "--short", "-q", "HEAD")
+	return branch
+}
+
+// RemoteBranchesContaining returns the remote-tracking branches commit is
+// reachable from, i.e. where it has been pushed to.
+func RemoteBranchesContaining(commit string) ([]string, error) {
+	out, err := output("for-each-ref", "--contains", commit, "--format=%(refname:short)", "refs/remotes")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// IsClean reports whether the index and tracked files match HEAD.
+// Untracked files are ignored.
+func IsClean() (bool, error) {
+	out, err := output("status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false, err
+	}
+	return out == "", nil
+}
+
+// RebaseOnto replays the commits after upstream on the current branch on
+// top of onto, as `git rebase --onto onto upstream`. Git's output goes to
+// stderr, when it stops on a conflict the user resolves it the usual way.
+func RebaseOnto(onto, upstream string) error {
+	cmd := exec.Command("git", "rebase", "--onto", onto, upstream)
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git rebase: %w", err)
+	}
+	return nil
+}
+
+// Index is a private index file. Trees are built in it with the usual
+// commands through GIT_INDEX_FILE, so the user's index, working tree and
+// stash are never touched. The zero Index is the repository's own index.
+type Index struct {
+	Path string
+}
+
+// CopyIndex returns a private index at path that starts as a copy of the
+// repository's index.
+func CopyIndex(path string) (Index, error) {
+	src, err := output("rev-parse", "--git-path", "index")
+	if err != nil {
+		return Index{}, err
+	}
+	data, err := os.ReadFile(src)
+	if os.IsNotExist(err) {
+		return Index{Path: path}, Index{Path: path}.ReadTree("") // nothing staged yet
+	}
+	if err != nil {
+		return Index{}, fmt.Errorf("reading the index: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		return Index{}, fmt.Errorf("copying the index: %w", err)
+	}
+	return Index{Path: path}, nil
+}
+
+// KnownFiles lists the files matching paths in the index or HEAD, the
+// ones git commit <paths> commits. Like git, it fails for a path that
+// matches no such file, e.g. an untracked one.
+func KnownFiles(paths ...string) ([]string, error) {
+	args := []string{"ls-files", "-z", "--error-unmatch"}
+	if _, err := ResolveRef("HEAD"); err == nil {
+		args = append(args, "--with-tree=HEAD")
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", append(append(args, "--"), paths...)...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			msg, _, _ = strings.Cut(strings.TrimPrefix(msg, "error: "), "\n")
+			return nil, errors.New(msg)
+		}
+		return nil, fmt.Errorf("git ls-files: %w", err)
+	}
+
+	var files []string
+	for _, f := range strings.Split(stdout.String(), "\x00") {
+		if f != "" && !slices.Contains(files, f) {
+			files = append(files, f)
+		}
+	}
+	return files, nil
+}
+
+// ReadTree resets the index to treeish, or empties it if treeish is "".
+func (ix Index) ReadTree(treeish string) error {
+	if treeish == "" {
+		_, err := ix.run("", "read-tree", "--empty")
+		return err
+	}
+	_, err := ix.run("", "read-tree", treeish)
+	return err
+}
+
+// Apply applies patch to the index. zeroContext patches need
+// --unidiff-zero, see Hunk.HasContext.
+func (ix Index) Apply(patch string, zeroContext bool) error {
+	args := []string{"apply", "--cached"}
+	if zeroContext {
+		args = append(args, "--unidiff-zero")
+	}
+	_, err := ix.run(patch, append(args, "-")...)
+	return err
+}
+
+// UpdateTracked stages every change to tracked files, like git commit -a.
+func (ix Index) UpdateTracked() error {
+	_, err := ix.run("", "add", "--update")
+	return err
+}
+
+// UpdateFiles stages files as they are in the work tree, adding new ones
+// and removing deleted ones, like git commit <paths> does. files must be
+// file names, see KnownFiles, directories are not expanded.
+func (ix Index) UpdateFiles(files ...string) error {
+	_, err := ix.run("", append([]string{"update-index", "--add", "--remove", "--"}, files...)...)
+	return err
+}
+
+// WriteTree writes the index as a tree object and returns its name.
+func (ix Index) WriteTree() (string, error) {
+	return ix.run("", "write-tree")
+}
+
+// Remove deletes the index file.
+func (ix Index) Remove() error {
+	if err := os.Remove(ix.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (ix Index) run(stdin string, args ...string) (string, error) {
+	return command(ix.env(), stdin, args...)
+}
+
+// env points git at the index file, the zero Index needs nothing.
+func (ix Index) env() []string {
+	if ix.Path == "" {
+		return nil
+	}
+	return []string{"GIT_INDEX_FILE=" + ix.Path}
+}
+
+// output runs git with args and returns its trimmed stdout.
+func output(args ...string) (string, error) {
+	return command(nil, "", args...)
+}
+
+// command runs git with extra environment and stdin and returns its trimmed
+// stdout. Stderr is included in the error to make failures actionable.
+func command(env []string, stdin string, args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+	if stdin != "" {
+		cmd.Stdin = strings.NewReader(stdin)
+	}
+
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return "", fmt.Errorf("git %s: %w: %s", args[0], err, msg)
+		}
+		return "", fmt.Errorf("git %s: %w", args[0], err)
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
@@ -0,0 +1,73 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newRepo creates an empty repository in a temp dir, changes into it and
+// isolates git from the user's config.
+func newRepo(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, ".gitconfig"))
+	t.Setenv("GIT_AUTHOR_NAME", "Ada")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ada@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Ada")
+	t.Setenv("GIT_COMMITTER_EMAIL", "ada@example.com")
+	run(t, "init", "-q", "-b", "main")
+}
+
+func run(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// commitFile writes content to name and commits it as Bob at a fixed date.
+func commitFile(t *testing.T, name, content, message string) string {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	run(t, "add", name)
+	run(t, "commit", "-q", "--author", "Bob <bob@example.com>", "--date", "1600000000 +0200", "-m", message)
+	return run(t, "rev-parse", "HEAD")
+}
+
+func TestCopyCommit(t *testing.T) {
+	newRepo(t)
+	root := commitFile(t, "a.txt", "a\n", "root")
+	second := commitFile(t, "b.txt", "b\n", "second")
+
+	// copied onto the root again, and as a new root
+	for _, parent := range []string{root, ""} {
+		copied, err := CopyCommit(second, parent, "feat: new message\n\nwith a body")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if copied == second {
+			t.Fatal("expected a new commit")
+		}
+		if got := run(t, "log", "-1", "--format=%an <%ae> %ad|%T|%P|%B", "--date=raw", copied); got != "Bob <bob@example.com> 1600000000 +0200|"+
+			run(t, "rev-parse", second+"^{tree}")+"|"+parent+"|feat: new message\n\nwith a body" {
+			t.Errorf("copy of %s onto %q:\n%s", second, parent, got)
+		}
+	}
+	if head := run(t, "rev-parse", "HEAD"); head != second {
+		t.Error("CopyCommit moved HEAD")
+	}
+}
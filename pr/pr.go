@@ -0,0 +1,220 @@
+// Package pr writes pull request titles and descriptions from the commits
+// of a branch, without talking to a hosting service.
+package pr
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/ademajagon/gix/internal/git"
+	"github.com/ademajagon/gix/provider"
+)
+
+// DefaultTemplate is used when the repository has no pull request template.
+const DefaultTemplate = `## Summary
+
+## Changes
+
+## Testing
+
+## Risk
+`
+
+// templatePaths are where GitHub looks for a pull request template,
+// relative to the repository root.
+var templatePaths = []string{
+	".github/pull_request_template.md",
+	".github/PULL_REQUEST_TEMPLATE.md",
+	"pull_request_template.md",
+	"PULL_REQUEST_TEMPLATE.md",
+	"docs/pull_request_template.md",
+	"docs/PULL_REQUEST_TEMPLATE.md",
+}
+
+// Branch is what a pull request is written from.
+type Branch struct {
+	Name string
+	// Base is the branch the pull request targets, MergeBase where the
+	// two diverged.
+	Base      string
+	MergeBase string
+	// Commits are the full messages of the commits after MergeBase,
+	// oldest first.
+	Commits  []string
+	Diff     string
+	Template string
+}
+
+// Description is a pull request title and Markdown body.
+type Description struct {
+	Title string `json:"title" desc:"single line under 72 characters"`
+	Body  string `json:"body" desc:"Markdown body following the template"`
+}
+
+// Validate trims the description and rejects an empty or multi-line title.
+func (d *Description) Validate() error {
+	d.Title = strings.TrimSpace(d.Title)
+	d.Body = strings.TrimSpace(d.Body)
+	switch {
+	case d.Title == "":
+		return errors.New("title is empty")
+	case strings.Contains(d.Title, "\n"):
+		return errors.New("title must be a single line")
+	case d.Body == "":
+		return errors.New("body is empty")
+	}
+	return nil
+}
+
+// String formats d like a commit message, title then body, so the first
+// line can go to --title and the rest to --body-file.
+func (d *Description) String() string {
+	return d.Title + "\n\n" + d.Body + "\n"
+}
+
+// Load collects the commits and diff of the current branch against base.
+// Without base the branch's upstream is used when it tracks another
+// branch, otherwise the default branch, see DefaultBase.
+func Load(base string, maxDiffBytes int) (*Branch, error) {
+	b := &Branch{Name: git.CurrentBranch(), Base: base}
+	if b.Base == "" {
+		b.Base = DefaultBase(b.Name)
+		if b.Base == "" {
+			return nil, errors.New("cannot tell which branch this pull request targets, pass --base")
+		}
+	}
+
+	var err error
+	if b.MergeBase, err = git.MergeBase(b.Base, "HEAD"); err != nil {
+		return nil, fmt.Errorf("no common ancestor with %s", b.Base)
+	}
+	commits, err := git.RevList("--reverse", "--no-merges", b.MergeBase+"..HEAD")
+	if err != nil {
+		return nil, err
+	}
+	if len(commits) == 0 {
+		return nil, fmt.Errorf("no commits since %s", b.Base)
+	}
+	for _, c := range commits {
+		msg, err := git.Message(c)
+		if err != nil {
+			return nil, err
+		}
+		b.Commits = append(b.Commits, msg)
+	}
+
+	if b.Diff, err = git.GetRangeDiff(b.MergeBase, "HEAD", maxDiffBytes); err != nil {
+		return nil, err
+	}
+
+	root, err := git.TopLevel()
+	if err != nil {
+		return nil, err
+	}
+	if b.Template, err = FindTemplate(root); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// DefaultBase returns the branch a pull request from branch most likely
+// targets: its upstream when that is another branch, e.g. a feature branch
+// created from origin/main, otherwise the remote's default branch, or a
+// local main or master.
+func DefaultBase(branch string) string {
+	if up := git.Upstream(branch); up != "" && !strings.HasSuffix(up, "/"+branch) {
+		return up
+	}
+	if head := git.RemoteHead("origin"); head != "" {
+		return head
+	}
+	for _, b := range []string{"main", "master"} {
+		if b != branch {
+			if _, err := git.ResolveRef(b); err == nil {
+				return b
+			}
+		}
+	}
+	return ""
+}
+
+// FindTemplate returns the repository's pull request template, or "" when
+// there is none.
+func FindTemplate(root string) (string, error) {
+	for _, p := range templatePaths {
+		data, err := os.ReadFile(filepath.Join(root, p))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", err
+		}
+		return string(data), nil
+	}
+	return "", nil
+}
+
+// Prompt is what the model is shown: the template, the commit messages and
+// the diff.
+func (b *Branch) Prompt() string {
+	template := b.Template
+	if strings.TrimSpace(template) == "" {
+		template = DefaultTemplate
+	}
+
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "Template:\n%s\n", strings.TrimSpace(template))
+	fmt.Fprintf(&sb, "\nCommits (%s into %s, oldest first):\n", b.Name, b.Base)
+	for _, c := range b.Commits {
+		fmt.Fprintf(&sb, "\n%s\n", strings.TrimSpace(c))
+	}
+	fmt.Fprintf(&sb, "\nDiff:\n%s\n", b.Diff)
+	return sb.String()
+}
+
+// Generate writes the description with c. Providers without a chat model,
+// like Local, get one templated from the commit messages, see Offline.
+func Generate(ctx context.Context, c provider.Chat, b *Branch) (*Description, error) {
+	if provider.IsLocalChat(c) {
+		return Offline(b), nil
+	}
+
+	var d Description
+	if err := provider.GeneratePullRequest(ctx, c, b.Prompt(), &d); err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// Offline builds a description from the commit messages alone: the title
+// of the first commit and a list of all of them, under the template when
+// the repository has one.
+func Offline(b *Branch) *Description {
+	var subjects []string
+	for _, c := range b.Commits {
+		subject, _, _ := strings.Cut(strings.TrimSpace(c), "\n")
+		subjects = append(subjects, subject)
+	}
+
+	var changes strings.Builder
+	for _, s := range subjects {
+		fmt.Fprintf(&changes, "- %s\n", s)
+	}
+
+	var body string
+	if strings.TrimSpace(b.Template) != "" {
+		body = strings.TrimSpace(b.Template) + "\n\n## Commits\n\n" + changes.String()
+	} else {
+		summary := subjects[0]
+		if len(subjects) > 1 {
+			summary = fmt.Sprintf("%d commits on %s.", len(subjects), b.Name)
+		}
+		body = fmt.Sprintf("## Summary\n\n%s\n\n## Changes\n\n%s\n## Testing\n\n\n## Risk\n", summary, changes.String())
+	}
+
+	return &Description{Title: subjects[0], Body: strings.TrimSpace(body)}
+}
@@ -0,0 +1,113 @@
+package pr
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ademajagon/gix/provider"
+)
+
+func TestDescription_Validate(t *testing.T) {
+	d := &Description{Title: "  feat: add pr  \n", Body: "\n## Summary\n\nx\n"}
+	if err := d.Validate(); err != nil {
+		t.Fatal(err)
+	}
+	if d.Title != "feat: add pr" || d.Body != "## Summary\n\nx" {
+		t.Errorf("not trimmed: %q %q", d.Title, d.Body)
+	}
+
+	for _, bad := range []Description{
+		{Title: "", Body: "x"},
+		{Title: "one\ntwo", Body: "x"},
+		{Title: "ok", Body: " "},
+	} {
+		if err := bad.Validate(); err == nil {
+			t.Errorf("%+v: expected an error", bad)
+		}
+	}
+}
+
+func TestFindTemplate(t *testing.T) {
+	root := t.TempDir()
+	if got, err := FindTemplate(root); err != nil || got != "" {
+		t.Fatalf("no template: got %q, %v", got, err)
+	}
+
+	dir := filepath.Join(root, ".github")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pull_request_template.md"), []byte("## Why\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := FindTemplate(root); err != nil || got != "## Why\n" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+}
+
+func TestPrompt_UsesTemplate(t *testing.T) {
+	b := &Branch{
+		Name:    "feature",
+		Base:    "origin/main",
+		Commits: []string{"feat: one\n\nbody\n", "fix: two\n"},
+		Diff:    "diff --git a/x b/x",
+	}
+	if got := b.Prompt(); !strings.Contains(got, "## Risk") || !strings.Contains(got, "feat: one\n\nbody") {
+		t.Errorf("default template or commits missing:\n%s", got)
+	}
+
+	b.Template = "## Why\n\n<!-- explain -->\n"
+	got := b.Prompt()
+	if !strings.Contains(got, "## Why") || strings.Contains(got, "## Risk") {
+		t.Errorf("repository template not used:\n%s", got)
+	}
+}
+
+func TestOffline(t *testing.T) {
+	b := &Branch{Name: "feature", Commits: []string{"feat: one\n\nbody\n", "fix: two\n"}}
+	d := Offline(b)
+	if d.Title != "feat: one" {
+		t.Errorf("title = %q", d.Title)
+	}
+	if !strings.Contains(d.Body, "- feat: one\n- fix: two") || !strings.Contains(d.Body, "2 commits on feature.") {
+		t.Errorf("body:\n%s", d.Body)
+	}
+	if err := d.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	b.Template = "## Why\n"
+	if d := Offline(b); !strings.HasPrefix(d.Body, "## Why\n\n## Commits\n\n- feat: one") {
+		t.Errorf("template body:\n%s", d.Body)
+	}
+}
+
+// remoteChat is a chat provider that also writes commit messages itself,
+// which must not make Generate fall back to Offline.
+type remoteChat struct{ content string }
+
+func (r remoteChat) Complete(context.Context, provider.Request) (*provider.Response, error) {
+	return &provider.Response{Text: r.content}, nil
+}
+
+func (r remoteChat) CommitMessage(string) (string, error) {
+	return "feat: unused", nil
+}
+
+func TestGenerate(t *testing.T) {
+	b := &Branch{Name: "feature", Base: "main", Commits: []string{"feat: add login"}}
+
+	d, err := Generate(context.Background(), provider.NewLocal(), b)
+	if err != nil || d.Title != "feat: add login" {
+		t.Fatalf("local: got %+v, %v", d, err)
+	}
+
+	chat := remoteChat{content: `{"title": "Add login", "body": "Adds a login page."}`}
+	d, err = Generate(context.Background(), chat, b)
+	if err != nil || d.Title != "Add login" {
+		t.Fatalf("remote: got %+v, %v", d, err)
+	}
+}
@@ -0,0 +1,83 @@
+package provider
+
+import (
+	"fmt"
+
+	"github.com/ademajagon/gix/cache"
+)
+
+// EmbeddingModeler is implemented by providers that can name the model
+// behind GetEmbeddings. Only their vectors can be cached, since a vector is
+// meaningless without knowing which model produced it.
+type EmbeddingModeler interface {
+	EmbeddingModel() string
+}
+
+// cachedEmbeddings serves embeddings from an on-disk cache and only asks
+// the wrapped provider for texts it has not seen before.
+type cachedEmbeddings struct {
+	Embedder
+	model string
+	store *cache.Store
+}
+
+// WithEmbeddingCache wraps e so repeated embeddings come from store. name
+// is the provider name, which together with the model keys the cache.
+// Providers that do not implement EmbeddingModeler are returned unchanged.
+func WithEmbeddingCache(e Embedder, name string, store *cache.Store) Embedder {
+	m, ok := e.(EmbeddingModeler)
+	if !ok || store == nil {
+		return e
+	}
+	return &cachedEmbeddings{Embedder: e, model: name + "/" + m.EmbeddingModel(), store: store}
+}
+
+// GetEmbeddings returns cached vectors where possible. Cache failures are
+// never fatal, the cache is only an optimisation.
+func (c *cachedEmbeddings) GetEmbeddings(texts []string) ([][]float32, error) {
+	result := make([][]float32, len(texts))
+	missing := make(map[string][]int) // text -> positions, so duplicates are embedded once
+	var queue []string
+
+	for i, text := range texts {
+		if vec, ok := c.store.Get(c.model, text); ok {
+			result[i] = vec
+			continue
+		}
+		if _, seen := missing[text]; !seen {
+			queue = append(queue, text)
+		}
+		missing[text] = append(missing[text], i)
+	}
+
+	if len(queue) == 0 {
+		return result, nil
+	}
+
+	vecs, err := c.Embedder.GetEmbeddings(queue)
+	if err != nil {
+		return nil, err
+	}
+	if len(vecs) != len(queue) {
+		return nil, fmt.Errorf("provider returned %d embeddings for %d texts", len(vecs), len(queue))
+	}
+
+	for i, text := range queue {
+		for _, pos := range missing[text] {
+			result[pos] = vecs[i]
+		}
+		_ = c.store.Put(c.model, text, vecs[i])
+	}
+	_ = c.store.Evict()
+
+	return result, nil
+}
+
+// EmbeddingBatchSize is the wrapped provider's limit.
+func (c *cachedEmbeddings) EmbeddingBatchSize() int {
+	return EmbeddingBatchSize(c.Embedder)
+}
+
+func (c *cachedEmbeddings) unwrapEmbedder() Embedder {
+	return c.Embedder
+}
@@ -1,11 +1,16 @@
 package provider
 
 import (
+	"context"
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
+
+	"github.com/ademajagon/gix/cache"
+	"github.com/ademajagon/gix/config"
 )
 
 func newTestChatClient(t *testing.T, chatHandler, embedHandler http.HandlerFunc) (*chatClient, *httptest.Server, *httptest.Server) {
@@ -45,7 +50,7 @@ func TestChatClient_GenerateCommitMessage_Success(t *testing.T) {
 	defer chatSrv.Close()
 	defer embedSrv.Close()
 
-	msg, err := c.GenerateCommitMessage("diff --git a/auth.go")
+	msg, err := GenerateCommitMessage(context.Background(), c, "diff --git a/auth.go")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -68,7 +73,7 @@ func TestChatClient_GenerateCommitMessage_TrimsWhitespace(t *testing.T) {
 	defer chatSrv.Close()
 	defer embedSrv.Close()
 
-	msg, err := c.GenerateCommitMessage("some diff")
+	msg, err := GenerateCommitMessage(context.Background(), c, "some diff")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -86,7 +91,7 @@ func TestChatClient_GenerateCommitMessage_NoChoices(t *testing.T) {
 	defer chatSrv.Close()
 	defer embedSrv.Close()
 
-	_, err := c.GenerateCommitMessage("some diff")
+	_, err := GenerateCommitMessage(context.Background(), c, "some diff")
 	if err == nil {
 		t.Fatal("expected error for empty choices, got nil")
 	}
@@ -104,7 +109,7 @@ func TestChatClient_GenerateCommitMessage_APIError(t *testing.T) {
 	defer chatSrv.Close()
 	defer embedSrv.Close()
 
-	_, err := c.GenerateCommitMessage("some diff")
+	_, err := GenerateCommitMessage(context.Background(), c, "some diff")
 	if err == nil {
 		t.Fatal("expected error, got nil")
 	}
@@ -130,7 +135,7 @@ func TestChatClient_GenerateCommitMessage_NoAPIKey(t *testing.T) {
 
 	c := newChatClient(chatSrv.URL, embedSrv.URL, "llama3.1", "nomic-embed-text", "", 5*time.Second)
 
-	msg, err := c.GenerateCommitMessage("diff")
+	msg, err := GenerateCommitMessage(context.Background(), c, "diff")
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -210,7 +215,7 @@ func TestChatClient_SendsCorrectModels(t *testing.T) {
 	defer chatSrv.Close()
 	defer embedSrv.Close()
 
-	c.GenerateCommitMessage("diff")
+	GenerateCommitMessage(context.Background(), c, "diff")
 	if receivedChatModel != "test-chat-model" {
 		t.Errorf("expected chat model %q, got %q", "test-chat-model", receivedChatModel)
 	}
@@ -220,3 +225,391 @@ func TestChatClient_SendsCorrectModels(t *testing.T) {
 		t.Errorf("expected embed model %q, got %q", "test-embed-model", receivedEmbedModel)
 	}
 }
+
+func TestChatClient_GenerateSplitPlan_UsesPlanPrompt(t *testing.T) {
+	var received chatRequest
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(chatResponse{
+			Choices: []chatChoice{{Message: chatMessage{Content: `{"commits": []}`}}},
+		})
+	}
+
+	c, chatSrv, embedSrv := newTestChatClient(t, handler, nil)
+	defer chatSrv.Close()
+	defer embedSrv.Close()
+
+	var plan struct {
+		Commits []struct {
+			Hunks []int `json:"hunks"`
+		} `json:"commits"`
+	}
+	if err := GenerateSplitPlan(context.Background(), c, "[1] a.go @@ -1 +1 @@\n+x\n", &plan); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(received.Messages) != 2 || received.Messages[0].Content != SplitPlanSystem {
+		t.Errorf("expected split plan system prompt, got %+v", received.Messages)
+	}
+	if received.MaxTokens <= 128 {
+		t.Errorf("expected a larger token budget for plans, got %d", received.MaxTokens)
+	}
+	if f := received.ResponseFormat; f == nil || f.Type != "json_schema" || !f.JSONSchema.Strict {
+		t.Errorf("expected a strict json_schema response format, got %+v", f)
+	}
+}
+
+func TestWithEmbeddingCache_OnlyEmbedsNewTexts(t *testing.T) {
+	var requested [][]string
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		var req embedRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		requested = append(requested, req.Input)
+
+		var resp embedResponse
+		for _, text := range req.Input {
+			resp.Data = append(resp.Data, struct {
+				Embedding []float32 `json:"embedding"`
+			}{Embedding: []float32{float32(len(text))}})
+		}
+		json.NewEncoder(w).Encode(resp)
+	}
+
+	c, chatSrv, embedSrv := newTestChatClient(t, nil, handler)
+	defer chatSrv.Close()
+	defer embedSrv.Close()
+
+	p := WithEmbeddingCache(c, "openai", &cache.Store{Dir: t.TempDir(), MaxBytes: cache.DefaultMaxBytes})
+
+	if _, err := p.GetEmbeddings([]string{"a", "bb", "a"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	result, err := p.GetEmbeddings([]string{"bb", "ccc", "a"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(requested) != 2 || len(requested[0]) != 2 || len(requested[1]) != 1 || requested[1][0] != "ccc" {
+		t.Errorf("expected [[a bb] [ccc]] to be requested, got %v", requested)
+	}
+	if result[0][0] != 2 || result[1][0] != 3 || result[2][0] != 1 {
+		t.Errorf("unexpected embeddings: %v", result)
+	}
+}
+
+func TestNewFromConfig_CombinesChatAndEmbedProviders(t *testing.T) {
+	t.Setenv("XDG_CACHE_HOME", t.TempDir())
+	t.Setenv("HOME", t.TempDir())
+
+	cfg := config.Config{Provider: ProviderGemini, GeminiKey: "g", EmbedProvider: ProviderOllama}
+
+	chat, err := NewChat(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := chat.(*Gemini); !ok {
+		t.Errorf("expected Gemini for chat, got %T", chat)
+	}
+
+	p, err := NewFromConfig(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := EmbeddingBatchSize(p); got != ollamaEmbedBatch {
+		t.Errorf("expected the Ollama batch size %d, got %d", ollamaEmbedBatch, got)
+	}
+}
+
+func TestNewFromConfig_MissingKeyForChatProvider(t *testing.T) {
+	cfg := config.Config{Provider: ProviderOllama, ChatProvider: ProviderOpenAI}
+	if _, err := NewFromConfig(cfg); err == nil {
+		t.Error("expected an error without an OpenAI key")
+	}
+}
+
+func TestIsLocal(t *testing.T) {
+	local := Combine(NewOllama("", "", ""), NewLocal())
+	if !IsLocal(local) {
+		t.Error("expected a combined local embedder to be detected")
+	}
+	if IsLocal(NewOllama("", "", "")) {
+		t.Error("expected Ollama not to be local")
+	}
+}
+
+func TestIsLocalChat(t *testing.T) {
+	if !IsLocalChat(Combine(NewLocal(), NewOllama("", "", ""))) {
+		t.Error("expected a combined local chat to be detected")
+	}
+	if IsLocalChat(Combine(NewOllama("", "", ""), NewLocal())) {
+		t.Error("expected Ollama chat not to be local")
+	}
+}
+
+func TestChatClient_Complete_SendsConversation(t *testing.T) {
+	var received chatRequest
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(chatResponse{
+			Choices: []chatChoice{{Message: chatMessage{Content: "  shorter  "}}},
+		})
+	}
+
+	c, chatSrv, embedSrv := newTestChatClient(t, handler, nil)
+	defer chatSrv.Close()
+	defer embedSrv.Close()
+
+	resp, err := c.Complete(context.Background(), Request{
+		System: "be brief",
+		Messages: []Message{
+			{Role: RoleUser, Content: "describe the diff"},
+			{Role: RoleAssistant, Content: "a long description"},
+			{Role: RoleUser, Content: "shorter please"},
+		},
+		MaxTokens:   64,
+		Temperature: 0.7,
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Text != "shorter" {
+		t.Errorf("expected trimmed text, got %q", resp.Text)
+	}
+
+	roles := make([]string, len(received.Messages))
+	for i, m := range received.Messages {
+		roles[i] = m.Role
+	}
+	if strings.Join(roles, ",") != "system,user,assistant,user" {
+		t.Errorf("unexpected roles %v", roles)
+	}
+	if received.MaxTokens != 64 || received.Temperature != 0.7 || received.ResponseFormat != nil {
+		t.Errorf("unexpected request options %+v", received)
+	}
+}
+
+// scriptedChat answers with replies in order and records the requests.
+type scriptedChat struct {
+	replies  []string
+	requests []Request
+}
+
+func (s *scriptedChat) Complete(_ context.Context, req Request) (*Response, error) {
+	s.requests = append(s.requests, req)
+	reply := s.replies[len(s.requests)-1]
+	return &Response{Text: reply}, nil
+}
+
+type testAnswer struct {
+	Title  string   `json:"title" desc:"one line"`
+	Labels []string `json:"labels"`
+	Score  int      `json:"score"`
+	Skip   string   `json:"-"`
+}
+
+func TestSchemaFor(t *testing.T) {
+	schema, err := SchemaFor(&testAnswer{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, _ := json.Marshal(schema)
+	want := `{"additionalProperties":false,"properties":{"labels":{"items":{"type":"string"},"type":"array"},` +
+		`"score":{"type":"integer"},"title":{"description":"one line","type":"string"}},` +
+		`"required":["title","labels","score"],"type":"object"}`
+	if string(data) != want {
+		t.Errorf("unexpected schema:\n%s\nwant:\n%s", data, want)
+	}
+
+	if _, err := SchemaFor("not a struct"); err == nil {
+		t.Error("expected an error for a non-struct")
+	}
+	if gemini, _ := json.Marshal(geminiSchema(schema)); strings.Contains(string(gemini), "additionalProperties") {
+		t.Errorf("expected additionalProperties to be dropped for Gemini: %s", gemini)
+	}
+}
+
+func TestCompleteJSON_RetriesOnceWithTheError(t *testing.T) {
+	c := &scriptedChat{replies: []string{
+		`{"title": "x", "labels": ["a"]}`,
+		"```json\n{\"title\": \"x\", \"labels\": [\"a\"], \"score\": 3}\n```",
+	}}
+
+	var got testAnswer
+	err := CompleteJSON(context.Background(), c, Request{
+		Messages: []Message{{Role: RoleUser, Content: "rate it"}},
+	}, &got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Score != 3 || got.Labels[0] != "a" {
+		t.Errorf("unexpected answer %+v", got)
+	}
+
+	if c.requests[0].JSONSchema == nil {
+		t.Error("expected the schema to be sent")
+	}
+	retry := c.requests[1].Messages
+	if len(retry) != 3 || retry[1].Role != RoleAssistant || !strings.Contains(retry[2].Content, `missing "score"`) {
+		t.Errorf("expected the invalid reply and the error in the retry, got %+v", retry)
+	}
+	if len(c.requests[0].Messages) != 1 {
+		t.Error("expected the retry not to change the first request")
+	}
+}
+
+func TestCompleteJSON_GivesUpAfterRetry(t *testing.T) {
+	c := &scriptedChat{replies: []string{"no", `{"title": 1}`}}
+
+	var got testAnswer
+	if err := CompleteJSON(context.Background(), c, Request{}, &got); err == nil {
+		t.Fatal("expected an error after two invalid responses")
+	}
+	if len(c.requests) != 2 {
+		t.Errorf("expected exactly one retry, got %d requests", len(c.requests))
+	}
+}
+
+func TestOllama_Complete_StructuredUsesFormat(t *testing.T) {
+	var received ollamaChatRequest
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/chat" {
+			t.Errorf("expected the native chat endpoint, got %s", r.URL.Path)
+		}
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(ollamaChatResponse{
+			Message: chatMessage{Role: "assistant", Content: `{"title": "t", "labels": [], "score": 1}`},
+		})
+	}))
+	defer srv.Close()
+
+	var got testAnswer
+	err := CompleteJSON(context.Background(), NewOllama(srv.URL, "", ""), Request{System: "s"}, &got)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.Title != "t" {
+		t.Errorf("unexpected answer %+v", got)
+	}
+	if received.Stream || received.Format["type"] != "object" || received.Messages[0].Role != "system" {
+		t.Errorf("unexpected request %+v", received)
+	}
+}
+
+func TestGenerateCommitMessages_UsesNativeCandidates(t *testing.T) {
+	var received chatRequest
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&received)
+		json.NewEncoder(w).Encode(chatResponse{Choices: []chatChoice{
+			{Message: chatMessage{Content: "feat: a"}},
+			{Message: chatMessage{Content: "feat: b "}},
+			{Message: chatMessage{Content: "feat: c"}},
+		}})
+	}
+
+	c, chatSrv, embedSrv := newTestChatClient(t, handler, nil)
+	defer chatSrv.Close()
+	defer embedSrv.Close()
+
+	got, err := GenerateCommitMessages(context.Background(), c, "diff", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(got, "|") != "feat: a|feat: b|feat: c" {
+		t.Errorf("unexpected candidates %q", got)
+	}
+	if received.N != 3 || received.Temperature == 0 {
+		t.Errorf("expected n=3 at a non-zero temperature, got %+v", received)
+	}
+}
+
+func TestGenerateCommitMessages_TopsUpWithVariedTemperatures(t *testing.T) {
+	c := &scriptedChat{replies: []string{"feat: a", "feat: a", "fix: b", "feat: a", "chore: c"}}
+
+	got, err := GenerateCommitMessages(context.Background(), c, "diff", 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if strings.Join(got, "|") != "feat: a|fix: b|chore: c" {
+		t.Errorf("expected distinct candidates, got %q", got)
+	}
+	if c.requests[1].Temperature == c.requests[2].Temperature {
+		t.Error("expected top-up requests at different temperatures")
+	}
+}
+
+func TestCommitSession_RefineKeepsHistory(t *testing.T) {
+	c := &scriptedChat{replies: []string{"feat: a", "feat(api): a", "feat(api): add migration"}}
+	s := NewCommitSession(c, "diff")
+	ctx := context.Background()
+
+	if _, err := s.Suggest(ctx, 1); err != nil {
+		t.Fatalf("Suggest: %v", err)
+	}
+	if _, err := s.Refine(ctx, "feat: a", "scope should be api", 1); err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+	got, err := s.Refine(ctx, "feat(api): a edited", "mention the migration", 1)
+	if err != nil {
+		t.Fatalf("Refine: %v", err)
+	}
+	if got[0] != "feat(api): add migration" {
+		t.Errorf("unexpected refinement %q", got)
+	}
+
+	last := c.requests[2].Messages
+	roles := make([]string, len(last))
+	for i, m := range last {
+		roles[i] = m.Role
+	}
+	if strings.Join(roles, ",") != "user,assistant,user,assistant,user" {
+		t.Fatalf("expected the whole conversation, got roles %v", roles)
+	}
+	if last[3].Content != "feat(api): a edited" || !strings.HasPrefix(last[4].Content, "mention the migration") {
+		t.Errorf("expected the edited message and the latest feedback, got %+v", last[3:])
+	}
+	if len(c.requests[0].Messages) != 1 {
+		t.Error("expected earlier requests to keep their own history")
+	}
+}
+
+// roundTripFunc lets a test answer requests sent to fixed URLs.
+type roundTripFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestGemini_Complete_SendsConversation(t *testing.T) {
+	var received geminiChatRequest
+	client := &http.Client{Transport: roundTripFunc(func(r *http.Request) (*http.Response, error) {
+		json.NewDecoder(r.Body).Decode(&received)
+		rec := httptest.NewRecorder()
+		json.NewEncoder(rec).Encode(geminiChatResponse{Candidates: []geminiCandidate{
+			{Content: geminiContent{Parts: []geminiPart{{Text: "fix: b"}}}},
+		}})
+		return rec.Result(), nil
+	})}
+
+	resp, err := NewGeminiWithClient("key", client).Complete(context.Background(), Request{
+		System: "be brief",
+		Messages: []Message{
+			{Role: RoleUser, Content: "diff"},
+			{Role: RoleAssistant, Content: "feat: a"},
+			{Role: RoleUser, Content: "it is a fix"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp.Text != "fix: b" {
+		t.Errorf("unexpected text %q", resp.Text)
+	}
+
+	roles := make([]string, len(received.Contents))
+	for i, c := range received.Contents {
+		roles[i] = c.Role
+	}
+	if strings.Join(roles, ",") != "user,model,user" || received.SystemInstruction == nil {
+		t.Errorf("unexpected request roles %v, system %+v", roles, received.SystemInstruction)
+	}
+}
@@ -2,6 +2,7 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -18,6 +19,10 @@ type chatClient struct {
 	embedModel string
 	apiKey     string
 	httpClient *http.Client
+
+	// embedBatch is the most inputs one embeddings request may carry, 0
+	// when the endpoint states no limit.
+	embedBatch int
 }
 
 func newChatClient(chatURL, embedURL, chatModel, embedModel, apiKey string, timeout time.Duration) *chatClient {
@@ -37,10 +42,23 @@ type chatMessage struct {
 }
 
 type chatRequest struct {
-	Model       string        `json:"model"`
-	Messages    []chatMessage `json:"messages"`
-	Temperature float32       `json:"temperature"`
-	MaxTokens   int           `json:"max_tokens"`
+	Model          string          `json:"model"`
+	Messages       []chatMessage   `json:"messages"`
+	Temperature    float32         `json:"temperature"`
+	MaxTokens      int             `json:"max_tokens"`
+	N              int             `json:"n,omitempty"`
+	ResponseFormat *responseFormat `json:"response_format,omitempty"`
+}
+
+type responseFormat struct {
+	Type       string          `json:"type"`
+	JSONSchema *jsonSchemaSpec `json:"json_schema,omitempty"`
+}
+
+type jsonSchemaSpec struct {
+	Name   string         `json:"name"`
+	Schema map[string]any `json:"schema"`
+	Strict bool           `json:"strict"`
 }
 
 type chatChoice struct {
@@ -67,35 +85,50 @@ type embedResponse struct {
 	} `json:"data"`
 }
 
-func (c *chatClient) GenerateCommitMessage(diff string) (string, error) {
+// Complete sends req to the chat completions endpoint.
+func (c *chatClient) Complete(ctx context.Context, req Request) (*Response, error) {
+	messages := make([]chatMessage, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		messages = append(messages, chatMessage{Role: m.Role, Content: m.Content})
+	}
+
 	payload := chatRequest{
-		Model: c.chatModel,
-		Messages: []chatMessage{
-			{Role: "system", Content: CommitMessageSystem},
-			{Role: "user", Content: CommitMessageUser + diff},
-		},
-		Temperature: 0,
-		MaxTokens:   128,
+		Model:       c.chatModel,
+		Messages:    messages,
+		Temperature: req.Temperature,
+		MaxTokens:   req.MaxTokens,
+	}
+	if req.Candidates > 1 {
+		payload.N = req.Candidates
+	}
+	if req.JSONSchema != nil {
+		payload.ResponseFormat = &responseFormat{
+			Type:       "json_schema",
+			JSONSchema: &jsonSchemaSpec{Name: "response", Schema: req.JSONSchema, Strict: true},
+		}
 	}
 
 	data, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("marshalling request: %w", err)
+		return nil, fmt.Errorf("marshalling request: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, c.chatURL, bytes.NewReader(data))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, c.chatURL, bytes.NewReader(data))
 	if err != nil {
-		return "", fmt.Errorf("creating request: %w", err)
+		return nil, fmt.Errorf("creating request: %w", err)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Content-Type", "application/json")
 	if c.apiKey != "" {
-		req.Header.Set("Authorization", "Bearer "+c.apiKey)
+		httpReq.Header.Set("Authorization", "Bearer "+c.apiKey)
 	}
 
-	res, err := c.httpClient.Do(req)
+	res, err := c.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("request failed: %w", err)
+		return nil, fmt.Errorf("request failed: %w", err)
 	}
 	defer res.Body.Close()
 
@@ -103,21 +136,37 @@ func (c *chatClient) GenerateCommitMessage(diff string) (string, error) {
 	if res.StatusCode != http.StatusOK {
 		var apiErr chatResponse
 		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != nil {
-			return "", fmt.Errorf("API error: %s", apiErr.Error.Message)
+			return nil, fmt.Errorf("API error: %s", apiErr.Error.Message)
 		}
-		return "", fmt.Errorf("API error: %s", res.Status)
+		return nil, fmt.Errorf("API error: %s", res.Status)
 	}
 
 	var response chatResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("decoding response: %w", err)
+		return nil, fmt.Errorf("decoding response: %w", err)
 	}
 
 	if len(response.Choices) == 0 {
-		return "", errors.New("no choices returned")
+		return nil, errors.New("no choices returned")
 	}
 
-	return strings.TrimSpace(response.Choices[0].Message.Content), nil
+	out := &Response{Text: strings.TrimSpace(response.Choices[0].Message.Content)}
+	if len(response.Choices) > 1 {
+		for _, choice := range response.Choices {
+			out.Choices = append(out.Choices, strings.TrimSpace(choice.Message.Content))
+		}
+	}
+	return out, nil
+}
+
+// EmbeddingModel names the model GetEmbeddings uses.
+func (c *chatClient) EmbeddingModel() string {
+	return c.embedModel
+}
+
+// EmbeddingBatchSize is the most texts one GetEmbeddings call may carry.
+func (c *chatClient) EmbeddingBatchSize() int {
+	return c.embedBatch
 }
 
 func (c *chatClient) GetEmbeddings(texts []string) ([][]float32, error) {
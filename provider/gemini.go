@@ -2,6 +2,7 @@ package provider
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -15,6 +16,7 @@ const (
 	geminiBaseURL    = "https://generativelanguage.googleapis.com/v1beta/models"
 	geminiChatModel  = "gemini-2.5-flash-latest"
 	geminiEmbedModel = "gemini-embedding-001"
+	geminiEmbedBatch = 100 // max requests per batchEmbedContents call
 )
 
 type Gemini struct {
@@ -46,8 +48,11 @@ type geminiChatRequest struct {
 }
 
 type geminiGenConfig struct {
-	Temperature     float32 `json:"temperature"`
-	MaxOutputTokens int     `json:"maxOutputTokens"`
+	Temperature      float32        `json:"temperature"`
+	MaxOutputTokens  int            `json:"maxOutputTokens"`
+	CandidateCount   int            `json:"candidateCount,omitempty"`
+	ResponseMimeType string         `json:"responseMimeType,omitempty"`
+	ResponseSchema   map[string]any `json:"responseSchema,omitempty"`
 }
 
 type geminiCandidate struct {
@@ -83,36 +88,52 @@ type geminiBatchEmbedResponse struct {
 	Error      *geminiAPIError   `json:"error,omitempty"`
 }
 
-func (g *Gemini) GenerateCommitMessage(diff string) (string, error) {
+// Complete sends req to generateContent. Gemini calls the assistant role
+// "model".
+func (g *Gemini) Complete(ctx context.Context, req Request) (*Response, error) {
 	url := fmt.Sprintf("%s/%s:generateContent?key=%s", geminiBaseURL, geminiChatModel, g.apiKey)
 
+	contents := make([]geminiContent, len(req.Messages))
+	for i, m := range req.Messages {
+		role := m.Role
+		if role == RoleAssistant {
+			role = "model"
+		}
+		contents[i] = geminiContent{Role: role, Parts: []geminiPart{{Text: m.Content}}}
+	}
+
 	payload := geminiChatRequest{
-		SystemInstruction: &geminiContent{
-			Parts: []geminiPart{{Text: CommitMessageSystem}},
-		},
-		Contents: []geminiContent{
-			{Role: "user", Parts: []geminiPart{{Text: CommitMessageUser + diff}}},
-		},
+		Contents: contents,
 		GenerationConfig: &geminiGenConfig{
-			Temperature:     0,
-			MaxOutputTokens: 128,
+			Temperature:     req.Temperature,
+			MaxOutputTokens: req.MaxTokens,
 		},
 	}
+	if req.Candidates > 1 {
+		payload.GenerationConfig.CandidateCount = req.Candidates
+	}
+	if req.System != "" {
+		payload.SystemInstruction = &geminiContent{Parts: []geminiPart{{Text: req.System}}}
+	}
+	if req.JSONSchema != nil {
+		payload.GenerationConfig.ResponseMimeType = "application/json"
+		payload.GenerationConfig.ResponseSchema = geminiSchema(req.JSONSchema)
+	}
 
 	data, err := json.Marshal(payload)
 	if err != nil {
-		return "", fmt.Errorf("marshalling request: %w", err)
+		return nil, fmt.Errorf("marshalling request: %w", err)
 	}
 
-	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(data))
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
 	if err != nil {
-		return "", fmt.Errorf("building request: %w", err)
+		return nil, fmt.Errorf("building request: %w", err)
 	}
-	req.Header.Set("Content-Type", "application/json")
+	httpReq.Header.Set("Content-Type", "application/json")
 
-	res, err := g.httpClient.Do(req)
+	res, err := g.httpClient.Do(httpReq)
 	if err != nil {
-		return "", fmt.Errorf("Gemini request: %w", err)
+		return nil, fmt.Errorf("Gemini request: %w", err)
 	}
 	defer res.Body.Close()
 
@@ -121,21 +142,67 @@ func (g *Gemini) GenerateCommitMessage(diff string) (string, error) {
 	if res.StatusCode != http.StatusOK {
 		var apiErr geminiChatResponse
 		if json.Unmarshal(body, &apiErr) == nil && apiErr.Error != nil {
-			return "", fmt.Errorf("Gemini API error: %s", apiErr.Error.Message)
+			return nil, fmt.Errorf("Gemini API error: %s", apiErr.Error.Message)
 		}
-		return "", fmt.Errorf("Gemini API error %s", res.Status)
+		return nil, fmt.Errorf("Gemini API error %s", res.Status)
 	}
 
 	var response geminiChatResponse
 	if err := json.Unmarshal(body, &response); err != nil {
-		return "", fmt.Errorf("decoding Gemini response: %w", err)
+		return nil, fmt.Errorf("decoding Gemini response: %w", err)
 	}
 
 	if len(response.Candidates) == 0 || len(response.Candidates[0].Content.Parts) == 0 {
-		return "", errors.New("Gemini returned no content")
+		return nil, errors.New("Gemini returned no content")
 	}
 
-	return strings.TrimSpace(response.Candidates[0].Content.Parts[0].Text), nil
+	out := &Response{Text: strings.TrimSpace(response.Candidates[0].Content.Parts[0].Text)}
+	if len(response.Candidates) > 1 {
+		for _, c := range response.Candidates {
+			if len(c.Content.Parts) > 0 {
+				out.Choices = append(out.Choices, strings.TrimSpace(c.Content.Parts[0].Text))
+			}
+		}
+	}
+	return out, nil
+}
+
+// geminiSchema copies schema without additionalProperties, Gemini's
+// OpenAPI subset rejects it and never adds properties anyway.
+func geminiSchema(schema map[string]any) map[string]any {
+	out := make(map[string]any, len(schema))
+	for k, v := range schema {
+		if k == "additionalProperties" {
+			continue
+		}
+		switch v := v.(type) {
+		case map[string]any:
+			if k == "properties" {
+				props := make(map[string]any, len(v))
+				for name, p := range v {
+					if p, ok := p.(map[string]any); ok {
+						props[name] = geminiSchema(p)
+					}
+				}
+				out[k] = props
+			} else {
+				out[k] = geminiSchema(v)
+			}
+		default:
+			out[k] = v
+		}
+	}
+	return out
+}
+
+// EmbeddingModel names the model GetEmbeddings uses.
+func (g *Gemini) EmbeddingModel() string {
+	return geminiEmbedModel
+}
+
+// EmbeddingBatchSize is the most texts one GetEmbeddings call may carry.
+func (g *Gemini) EmbeddingBatchSize() int {
+	return geminiEmbedBatch
 }
 
 func (g *Gemini) GetEmbeddings(texts []string) ([][]float32, error) {
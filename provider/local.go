@@ -0,0 +1,303 @@
+package provider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"path"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	// localDims is the size of the hashed vectors. Hunks rarely have more
+	// than a few hundred distinct tokens, collisions stay rare.
+	localDims = 1024
+	// Tokens from the file path count more than tokens from code, files in
+	// the same place usually change together.
+	localPathWeight = 2.0
+	// Context lines describe where a change is, not what it does.
+	localContextWeight = 0.5
+)
+
+// localStopwords are tokens too common in code to say anything about a hunk.
+var localStopwords = map[string]bool{
+	"if": true, "else": true, "for": true, "range": true, "return": true,
+	"func": true, "var": true, "const": true, "type": true, "struct": true,
+	"nil": true, "err": true, "true": true, "false": true, "the": true,
+	"and": true, "of": true, "to": true, "in": true, "is": true, "a": true,
+	"go": true, "string": true, "int": true, "error": true, "fmt": true,
+}
+
+// Local is a provider that needs neither network nor model. Embeddings are
+// TF-IDF vectors over identifiers and path segments, hashed into a fixed
+// number of dimensions, and commit messages are templated from the changed
+// file paths. It keeps `gix split` working offline and in tests.
+//
+// IDF is computed over the texts of each GetEmbeddings call, so vectors
+// from different calls are not comparable. Local therefore accepts any
+// batch size and does not implement EmbeddingModeler, which keeps its
+// vectors out of the embedding cache.
+type Local struct{}
+
+func NewLocal() *Local {
+	return &Local{}
+}
+
+// EmbeddingBatchSize is unlimited, all hunks must share one IDF.
+func (l *Local) EmbeddingBatchSize() int {
+	return math.MaxInt32
+}
+
+func (l *Local) GetEmbeddings(texts []string) ([][]float32, error) {
+	docs := make([]map[string]float64, len(texts))
+	df := make(map[string]int)
+	for i, text := range texts {
+		docs[i] = localTerms(text)
+		for term := range docs[i] {
+			df[term]++
+		}
+	}
+
+	n := float64(len(texts))
+	result := make([][]float32, len(texts))
+	for i, terms := range docs {
+		vec := make([]float64, localDims)
+		for term, tf := range terms {
+			idf := math.Log((1+n)/(1+float64(df[term]))) + 1
+			bucket, sign := localHash(term)
+			vec[bucket] += sign * (1 + math.Log(tf)) * idf
+		}
+		result[i] = normalize(vec)
+	}
+	return result, nil
+}
+
+// Complete fails, Local has no model to answer free-form prompts. Commit
+// messages are templated instead, see CommitMessage.
+func (l *Local) Complete(context.Context, Request) (*Response, error) {
+	return nil, errors.New("the local provider has no chat model")
+}
+
+// CommitMessage templates a conventional commit line from the files in
+// diff, e.g. "test(provider): update local_test.go".
+func (l *Local) CommitMessage(diff string) (string, error) {
+	var files, added, deleted []string
+	for _, line := range strings.Split(diff, "\n") {
+		switch {
+		case strings.HasPrefix(line, "diff --git "):
+			if fields := strings.Fields(line); len(fields) >= 4 {
+				file := strings.TrimPrefix(fields[3], "b/")
+				if !slices.Contains(files, file) {
+					files = append(files, file)
+				}
+			}
+		case strings.HasPrefix(line, "new file") && len(files) > 0 && !slices.Contains(added, last(files)):
+			added = append(added, last(files))
+		case strings.HasPrefix(line, "deleted file") && len(files) > 0 && !slices.Contains(deleted, last(files)):
+			deleted = append(deleted, last(files))
+		}
+	}
+	if len(files) == 0 {
+		return "", errors.New("no files in diff")
+	}
+
+	verb := "update"
+	switch {
+	case len(added) == len(files):
+		verb = "add"
+	case len(deleted) == len(files):
+		verb = "remove"
+	}
+
+	msg := localType(files, len(added) == len(files))
+	if scope := localScope(files); scope != "" {
+		msg += "(" + scope + ")"
+	}
+	return msg + ": " + verb + " " + listFiles(files), nil
+}
+
+// localTerms counts the weighted tokens of an embedding text: the first line
+// is the file path, hunk headers contribute their function context.
+func localTerms(text string) map[string]float64 {
+	terms := make(map[string]float64)
+	add := func(s string, weight float64) {
+		for _, tok := range tokenize(s) {
+			terms[tok] += weight
+		}
+	}
+
+	lines := strings.Split(text, "\n")
+	add(strings.NewReplacer("/", " ", ".", " ").Replace(lines[0]), localPathWeight)
+
+	for _, line := range lines[1:] {
+		switch {
+		case strings.HasPrefix(line, "diff --git"), strings.HasPrefix(line, "index "),
+			strings.HasPrefix(line, "--- "), strings.HasPrefix(line, "+++ "):
+		case strings.HasPrefix(line, "@@"):
+			if _, ctx, ok := strings.Cut(strings.TrimPrefix(line, "@@"), "@@"); ok {
+				add(ctx, localContextWeight)
+			}
+		case strings.HasPrefix(line, "+"), strings.HasPrefix(line, "-"):
+			add(line[1:], 1)
+		default:
+			add(line, localContextWeight)
+		}
+	}
+	return terms
+}
+
+// tokenize splits s into lower-case identifiers and their camelCase and
+// snake_case parts, so "parseHunkHeader" also matches "hunk".
+func tokenize(s string) []string {
+	var tokens []string
+	words := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_'
+	})
+	for _, word := range words {
+		parts := splitIdentifier(word)
+		if len(parts) > 1 {
+			tokens = appendToken(tokens, strings.ToLower(word))
+		}
+		for _, part := range parts {
+			tokens = appendToken(tokens, strings.ToLower(part))
+		}
+	}
+	return tokens
+}
+
+func appendToken(tokens []string, tok string) []string {
+	if len(tok) < 2 || localStopwords[tok] || unicode.IsDigit(rune(tok[0])) {
+		return tokens
+	}
+	return append(tokens, tok)
+}
+
+// splitIdentifier splits on underscores and lower-to-upper case changes,
+// keeping acronyms together: "HTTPClient_new" -> HTTP, Client, new.
+func splitIdentifier(word string) []string {
+	var parts []string
+	for _, chunk := range strings.Split(word, "_") {
+		runes := []rune(chunk)
+		start := 0
+		for i := 1; i < len(runes); i++ {
+			lowerToUpper := unicode.IsLower(runes[i-1]) && unicode.IsUpper(runes[i])
+			acronymEnd := i+1 < len(runes) && unicode.IsUpper(runes[i-1]) &&
+				unicode.IsUpper(runes[i]) && unicode.IsLower(runes[i+1])
+			if lowerToUpper || acronymEnd {
+				parts = append(parts, string(runes[start:i]))
+				start = i
+			}
+		}
+		if start < len(runes) {
+			parts = append(parts, string(runes[start:]))
+		}
+	}
+	return parts
+}
+
+// localHash picks the bucket and sign of a term. The sign keeps colliding
+// terms from always adding up.
+func localHash(term string) (int, float64) {
+	h := fnv.New64a()
+	h.Write([]byte(term))
+	sum := h.Sum64()
+	if sum>>63 == 1 {
+		return int(sum % localDims), -1
+	}
+	return int(sum % localDims), 1
+}
+
+func normalize(vec []float64) []float32 {
+	var norm float64
+	for _, v := range vec {
+		norm += v * v
+	}
+	norm = math.Sqrt(norm)
+
+	out := make([]float32, len(vec))
+	if norm == 0 {
+		return out
+	}
+	for i, v := range vec {
+		out[i] = float32(v / norm)
+	}
+	return out
+}
+
+// localType guesses the conventional commit type from the file names.
+func localType(files []string, allNew bool) string {
+	all := func(match func(string) bool) bool {
+		for _, f := range files {
+			if !match(f) {
+				return false
+			}
+		}
+		return true
+	}
+
+	switch {
+	case all(func(f string) bool {
+		return strings.HasSuffix(f, ".md") || strings.HasPrefix(f, "docs/")
+	}):
+		return "docs"
+	case all(func(f string) bool {
+		return strings.HasSuffix(f, "_test.go") || strings.Contains(f, "testdata/")
+	}):
+		return "test"
+	case all(func(f string) bool { return strings.HasPrefix(f, ".github/") }):
+		return "ci"
+	case all(func(f string) bool {
+		base := path.Base(f)
+		return base == "go.mod" || base == "go.sum" || base == "Makefile"
+	}):
+		return "build"
+	case allNew:
+		return "feat"
+	default:
+		return "chore"
+	}
+}
+
+// localScope is the directory all files share, if they share one.
+func localScope(files []string) string {
+	dir := path.Dir(files[0])
+	for _, f := range files[1:] {
+		if path.Dir(f) != dir {
+			return ""
+		}
+	}
+	if dir == "." {
+		return ""
+	}
+	return path.Base(dir)
+}
+
+// last returns the most recent file, "" before the first one.
+func last(files []string) string {
+	if len(files) == 0 {
+		return ""
+	}
+	return files[len(files)-1]
+}
+
+func listFiles(files []string) string {
+	names := make([]string, len(files))
+	for i, f := range files {
+		names[i] = path.Base(f)
+	}
+
+	switch len(names) {
+	case 1:
+		return names[0]
+	case 2:
+		return names[0] + " and " + names[1]
+	case 3:
+		return fmt.Sprintf("%s, %s and %s", names[0], names[1], names[2])
+	default:
+		return fmt.Sprintf("%s, %s and %d more files", names[0], names[1], len(names)-2)
+	}
+}
@@ -0,0 +1,84 @@
+package provider
+
+import (
+	"math"
+	"reflect"
+	"testing"
+)
+
+func cosine(a, b []float32) float64 {
+	var dot float64
+	for i := range a {
+		dot += float64(a[i]) * float64(b[i])
+	}
+	return dot
+}
+
+func TestLocal_GetEmbeddings_RelatedHunksAreCloser(t *testing.T) {
+	texts := []string{
+		"split/cluster.go\n@@ -1 +1 @@\n+func embedHunks(p provider.AIProvider) {}",
+		"split/llm.go\n@@ -5 +5 @@\n+\tvecs := embedHunks(p)",
+		"README.md\n@@ -1 +1 @@\n+Install with brew",
+	}
+
+	vecs, err := NewLocal().GetEmbeddings(texts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vecs) != 3 || len(vecs[0]) != localDims {
+		t.Fatalf("expected 3 vectors of %d dims, got %d", localDims, len(vecs))
+	}
+
+	related, unrelated := cosine(vecs[0], vecs[1]), cosine(vecs[0], vecs[2])
+	if related <= unrelated {
+		t.Errorf("expected hunks sharing identifiers to be closer: %v <= %v", related, unrelated)
+	}
+	if norm := cosine(vecs[0], vecs[0]); math.Abs(norm-1) > 1e-5 {
+		t.Errorf("expected unit vectors, got norm %v", norm)
+	}
+}
+
+func TestTokenize_SplitsIdentifiers(t *testing.T) {
+	got := tokenize("parseHunkHeader(HTTPClient, max_bytes) if 42")
+	want := []string{"parsehunkheader", "parse", "hunk", "header", "httpclient", "http", "client", "max_bytes", "max", "bytes"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("tokenize() = %v, want %v", got, want)
+	}
+}
+
+func TestLocal_GenerateCommitMessage(t *testing.T) {
+	cases := []struct {
+		name string
+		diff string
+		want string
+	}{
+		{
+			name: "docs",
+			diff: "diff --git a/README.md b/README.md\n@@ -1 +1 @@\n+x",
+			want: "docs: update README.md",
+		},
+		{
+			name: "new files share a scope",
+			diff: "diff --git a/provider/local.go b/provider/local.go\nnew file mode 100644\n@@ -0,0 +1 @@\n+x\n" +
+				"diff --git a/provider/local_test.go b/provider/local_test.go\nnew file mode 100644\n@@ -0,0 +1 @@\n+x",
+			want: "feat(provider): add local.go and local_test.go",
+		},
+		{
+			name: "many files",
+			diff: "diff --git a/a.go b/a.go\ndiff --git a/cmd/b.go b/cmd/b.go\ndiff --git a/c.go b/c.go\ndiff --git a/d.go b/d.go",
+			want: "chore: update a.go, b.go and 2 more files",
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := NewLocal().CommitMessage(tc.diff)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
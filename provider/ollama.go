@@ -1,6 +1,15 @@
 package provider
 
-import "time"
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
 
 const (
 	// Defaults, can be configured via gix config
@@ -10,9 +19,16 @@ const (
 	// Do NOT use llama3.2 for embeddings since it's a generative model
 	// and will produce poor results for gix split's cosine clustering.
 	ollamaDefaultEmbedModel = "nomic-embed-text"
+	// Ollama has no request limit, but a local model embeds one batch at a
+	// time, smaller batches keep progress visible and requests under the
+	// client timeout.
+	ollamaEmbedBatch = 32
 )
 
-type Ollama struct{ *chatClient }
+type Ollama struct {
+	*chatClient
+	baseURL string
+}
 
 func NewOllama(baseURL, chatModel, embedModel string) *Ollama {
 	if baseURL == "" {
@@ -25,12 +41,85 @@ func NewOllama(baseURL, chatModel, embedModel string) *Ollama {
 		embedModel = ollamaDefaultEmbedModel
 	}
 
-	return &Ollama{newChatClient(
+	c := newChatClient(
 		baseURL+"/v1/chat/completions",
 		baseURL+"/v1/embeddings",
 		chatModel,
 		embedModel,
 		"", // ollama does not require api key
 		60*time.Second,
-	)}
+	)
+	c.embedBatch = ollamaEmbedBatch
+	return &Ollama{chatClient: c, baseURL: baseURL}
+}
+
+type ollamaChatRequest struct {
+	Model    string         `json:"model"`
+	Messages []chatMessage  `json:"messages"`
+	Stream   bool           `json:"stream"`
+	Format   map[string]any `json:"format"`
+	Options  ollamaOptions  `json:"options"`
+}
+
+type ollamaOptions struct {
+	Temperature float32 `json:"temperature"`
+	NumPredict  int     `json:"num_predict,omitempty"`
+}
+
+type ollamaChatResponse struct {
+	Message chatMessage `json:"message"`
+	Error   string      `json:"error"`
+}
+
+// Complete uses the OpenAI-compatible endpoint, except for structured
+// requests: those go to the native /api/chat, whose format parameter
+// constrains the model to the schema.
+func (o *Ollama) Complete(ctx context.Context, req Request) (*Response, error) {
+	if req.JSONSchema == nil {
+		return o.chatClient.Complete(ctx, req)
+	}
+
+	messages := make([]chatMessage, 0, len(req.Messages)+1)
+	if req.System != "" {
+		messages = append(messages, chatMessage{Role: "system", Content: req.System})
+	}
+	for _, m := range req.Messages {
+		messages = append(messages, chatMessage{Role: m.Role, Content: m.Content})
+	}
+
+	data, err := json.Marshal(ollamaChatRequest{
+		Model:    o.chatModel,
+		Messages: messages,
+		Format:   req.JSONSchema,
+		Options:  ollamaOptions{Temperature: req.Temperature, NumPredict: req.MaxTokens},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshalling request: %w", err)
+	}
+
+	httpReq, err := http.NewRequestWithContext(ctx, http.MethodPost, o.baseURL+"/api/chat", bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("creating request: %w", err)
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	res, err := o.httpClient.Do(httpReq)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, _ := io.ReadAll(res.Body)
+	var response ollamaChatResponse
+	if err := json.Unmarshal(body, &response); err != nil && res.StatusCode == http.StatusOK {
+		return nil, fmt.Errorf("decoding response: %w", err)
+	}
+	if res.StatusCode != http.StatusOK {
+		if response.Error != "" {
+			return nil, fmt.Errorf("API error: %s", response.Error)
+		}
+		return nil, fmt.Errorf("API error: %s", res.Status)
+	}
+
+	return &Response{Text: strings.TrimSpace(response.Message.Content)}, nil
 }
@@ -7,17 +7,20 @@ const (
 	openaiEmbedURL   = "https://api.openai.com/v1/embeddings"
 	openaiChatModel  = "gpt-4o"
 	openaiEmbedModel = "text-embedding-3-small"
+	openaiEmbedBatch = 2048 // max inputs per embeddings request
 )
 
 type OpenAI struct{ *chatClient }
 
 func NewOpenAI(apiKey string) *OpenAI {
-	return &OpenAI{newChatClient(
+	c := newChatClient(
 		openaiChatURL,
 		openaiEmbedURL,
 		openaiChatModel,
 		openaiEmbedModel,
 		apiKey,
 		20*time.Second,
-	)}
+	)
+	c.embedBatch = openaiEmbedBatch
+	return &OpenAI{c}
 }
@@ -1,11 +1,131 @@
 package provider
 
-// AIProvider is the core abstraction for AI providers.
-type AIProvider interface {
-	GenerateCommitMessage(diff string) (string, error)
+import "context"
+
+// Chat is a provider with a chat model. Features build their prompts on
+// top of Complete, see GenerateCommitMessage, so new commands work with
+// every provider without new interface methods.
+type Chat interface {
+	Complete(ctx context.Context, req Request) (*Response, error)
+}
+
+// Message roles.
+const (
+	RoleUser      = "user"
+	RoleAssistant = "assistant"
+)
+
+// Message is one turn of a conversation.
+type Message struct {
+	Role    string
+	Content string
+}
+
+// Request is a provider-agnostic chat completion request.
+type Request struct {
+	System      string
+	Messages    []Message
+	MaxTokens   int
+	Temperature float32
+	// JSONSchema, if set, asks the model for JSON matching this schema.
+	JSONSchema map[string]any
+	// Candidates asks for several alternative answers in one request.
+	// Providers that cannot return more than one ignore it.
+	Candidates int
+}
+
+// Response is the model's answer, with surrounding whitespace trimmed.
+type Response struct {
+	Text string
+	// Choices holds every answer when several candidates were returned,
+	// Text is the first of them.
+	Choices []string
+}
+
+// CommitMessager is implemented by providers that write commit messages
+// without a chat model, like Local. GenerateCommitMessage prefers it.
+type CommitMessager interface {
+	CommitMessage(diff string) (string, error)
+}
+
+// GenerateCommitMessage asks c for a conventional commit message for diff.
+func GenerateCommitMessage(ctx context.Context, c Chat, diff string) (string, error) {
+	if m, ok := c.(CommitMessager); ok {
+		return m.CommitMessage(diff)
+	}
+
+	resp, err := c.Complete(ctx, Request{
+		System:    CommitMessageSystem,
+		Messages:  []Message{{Role: RoleUser, Content: CommitMessageUser + diff}},
+		MaxTokens: 128,
+	})
+	if err != nil {
+		return "", err
+	}
+	return resp.Text, nil
+}
+
+// GenerateSplitPlan asks c to group numbered hunk summaries into commits
+// and decodes the plan into plan, see CompleteJSON.
+func GenerateSplitPlan(ctx context.Context, c Chat, hunks string, plan any) error {
+	return CompleteJSON(ctx, c, Request{
+		System:    SplitPlanSystem,
+		Messages:  []Message{{Role: RoleUser, Content: SplitPlanUser + hunks}},
+		MaxTokens: 2048,
+	}, plan)
+}
+
+// GeneratePullRequest asks c for the title and description of a pull
+// request from its commits and diff, and decodes them into pr, see
+// CompleteJSON.
+func GeneratePullRequest(ctx context.Context, c Chat, change string, pr any) error {
+	return CompleteJSON(ctx, c, Request{
+		System:    PullRequestSystem,
+		Messages:  []Message{{Role: RoleUser, Content: PullRequestUser + change}},
+		MaxTokens: 2048,
+	}, pr)
+}
+
+// GenerateReleaseNotes asks c to describe numbered commits for the users
+// of a release, and decodes the notes into notes, see CompleteJSON.
+func GenerateReleaseNotes(ctx context.Context, c Chat, commits string, notes any) error {
+	return CompleteJSON(ctx, c, Request{
+		System:    ReleaseNotesSystem,
+		Messages:  []Message{{Role: RoleUser, Content: ReleaseNotesUser + commits}},
+		MaxTokens: 2048,
+	}, notes)
+}
+
+// Embedder is a provider with an embedding model, used to cluster hunks.
+type Embedder interface {
 	GetEmbeddings(texts []string) ([][]float32, error)
 }
 
+// AIProvider has both capabilities, as `gix split` needs. The two may come
+// from different backends, see Combine.
+type AIProvider interface {
+	Chat
+	Embedder
+}
+
+// DefaultEmbeddingBatchSize is how many texts are embedded per request for
+// providers that do not state a limit.
+const DefaultEmbeddingBatchSize = 100
+
+// EmbeddingBatcher is implemented by providers that limit how many texts a
+// single GetEmbeddings call may carry.
+type EmbeddingBatcher interface {
+	EmbeddingBatchSize() int
+}
+
+// EmbeddingBatchSize returns the most texts p accepts per GetEmbeddings call.
+func EmbeddingBatchSize(p Embedder) int {
+	if b, ok := p.(EmbeddingBatcher); ok && b.EmbeddingBatchSize() > 0 {
+		return b.EmbeddingBatchSize()
+	}
+	return DefaultEmbeddingBatchSize
+}
+
 const CommitMessageSystem = "You are a conventional commit message generator. You only output commit messages, nothing else."
 
 const CommitMessageUser = `You must output ONLY a single conventional commit message. No explanations. No descriptions. No extra text.
@@ -38,3 +158,51 @@ Diff:
 %s
 
 Conventional commit message:`
+
+const SplitPlanSystem = "You are a git history editor. You group diff hunks into logical, atomic conventional commits and only output JSON, nothing else."
+
+const SplitPlanUser = `Group the numbered diff hunks below into logical commits. Hunks belong together when they implement the same change, not merely when they look alike: a new function and its call sites, a config field and the code reading it, a feature and its tests.
+
+Rules:
+- Every hunk number must appear in exactly one commit.
+- List commits in the order they should be applied, definitions before their uses.
+- Each message is a single conventional commit line: <type>(<optional scope>): <description>
+
+Output ONLY a JSON object in this shape, no markdown fences:
+{"commits": [{"hunks": [1, 3], "message": "feat(provider): add ollama support"}, {"hunks": [2], "message": "docs: describe ollama setup"}]}
+
+Hunks:
+`
+
+const PullRequestSystem = "You are a senior engineer writing pull request descriptions for reviewers. You only output JSON, nothing else."
+
+const PullRequestUser = `Write the title and Markdown body of a pull request for the change below.
+
+Rules:
+- The title is a single line under 72 characters, in the style of the commit messages.
+- Fill in the template given below: keep its headings and checklists, replace placeholders and comments with content. Leave a section short rather than inventing facts.
+- Describe what changed and why, for a reviewer who has not seen the code. Group related commits instead of listing every one.
+- Testing notes say how the change can be verified, based on the tests and code in the diff.
+- Risk names what could break, migrations, and anything deliberately left out.
+
+Output ONLY a JSON object in this shape, no markdown fences:
+{"title": "feat(api): add pagination to list endpoints", "body": "## Summary\n..."}
+
+`
+
+const ReleaseNotesSystem = "You are a technical writer turning commit logs into release notes for the users of a project. You only output JSON, nothing else."
+
+const ReleaseNotesUser = `Write release notes for the numbered commits below.
+
+Rules:
+- The summary is one or two sentences on what the release brings, for users, not developers.
+- Each entry is one line describing a change in plain language: what users can now do or what no longer goes wrong. No commit types, scopes or hashes.
+- An entry may cover several commits that make up one change. List each commit number in at most one entry.
+- Commits left out of every entry are listed with their commit subject.
+- Keep breaking changes explicit and say what users have to do.
+
+Output ONLY a JSON object in this shape, no markdown fences:
+{"summary": "Commit messages can now be refined with feedback.", "entries": [{"commits": [1, 3], "text": "Give feedback on a suggested commit message to get a revised one"}]}
+
+Commits:
+`
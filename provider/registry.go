@@ -1,8 +1,10 @@
 package provider
 
 import (
+	"errors"
 	"fmt"
 
+	"github.com/ademajagon/gix/cache"
 	"github.com/ademajagon/gix/config"
 )
 
@@ -10,33 +12,142 @@ const (
 	ProviderOpenAI = "openai"
 	ProviderGemini = "gemini"
 	ProviderOllama = "ollama"
+	ProviderLocal  = "local"
 )
 
-// New returns an AIProvider for the given name and API key.
-func New(name, apiKey string) (AIProvider, error) {
+// ErrNoChatModel and ErrNoEmbeddingModel are wrapped by NewChat and
+// NewEmbedder when the chosen provider lacks the capability.
+var (
+	ErrNoChatModel      = errors.New("has no chat model")
+	ErrNoEmbeddingModel = errors.New("has no embedding model")
+)
+
+// newProvider returns the named provider configured from cfg. Depending on
+// the backend it implements Chat, Embedder or both; NewChat and NewEmbedder
+// pick the capability.
+func newProvider(name string, cfg config.Config) (any, error) {
 	switch name {
 	case ProviderOpenAI:
-		if apiKey == "" {
+		if cfg.KeyFor(name) == "" {
 			return nil, fmt.Errorf("API key is required for provider %q, run `gix config set-key`", name)
 		}
-		return NewOpenAI(apiKey), nil
+		return NewOpenAI(cfg.KeyFor(name)), nil
 	case ProviderGemini:
-		if apiKey == "" {
-			return nil, fmt.Errorf("API key is required for provider %q, run `gix config set-key`", name)
+		if cfg.KeyFor(name) == "" {
+			return nil, fmt.Errorf("API key is required for provider %q, run `gix config set-key --provider gemini`", name)
 		}
-		return NewGemini(apiKey), nil
+		return NewGemini(cfg.KeyFor(name)), nil
 	case ProviderOllama:
-		return NewOllama("", "", ""), nil
+		return NewOllama(cfg.OllamaBaseURL, cfg.OllamaChatModel, cfg.OllamaEmbedModel), nil
+	case ProviderLocal:
+		return NewLocal(), nil
 	default:
-		return nil, fmt.Errorf("unknown provider %q (supported: openai, gemini, ollama)", name)
+		return nil, fmt.Errorf("unknown provider %q (supported: openai, gemini, ollama, local)", name)
+	}
+}
+
+// NewChat returns the provider that writes commit messages and split plans,
+// cfg's chat provider.
+func NewChat(cfg config.Config) (Chat, error) {
+	name := cfg.ResolveChatProvider()
+	p, err := newProvider(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	c, ok := p.(Chat)
+	if !ok {
+		return nil, fmt.Errorf("provider %q %w, choose another with `gix config set-provider --chat`", name, ErrNoChatModel)
 	}
+	return c, nil
 }
 
+// NewEmbedder returns cfg's embedding provider, with embeddings cached on
+// disk unless the cache is disabled.
+func NewEmbedder(cfg config.Config) (Embedder, error) {
+	name := cfg.ResolveEmbedProvider()
+	p, err := newProvider(name, cfg)
+	if err != nil {
+		return nil, err
+	}
+	e, ok := p.(Embedder)
+	if !ok {
+		return nil, fmt.Errorf("provider %q %w, choose another with `gix config set-provider --embed`", name, ErrNoEmbeddingModel)
+	}
+
+	if cfg.DisableEmbeddingCache {
+		return e, nil
+	}
+	store, err := cache.Open(cfg.EmbeddingCacheBytes())
+	if err != nil {
+		return e, nil // no cache dir, run without one
+	}
+	return WithEmbeddingCache(e, name, store), nil
+}
+
+// NewFromConfig returns a provider with both capabilities, as `gix split`
+// needs, combining the chat and embedding providers chosen in cfg.
 func NewFromConfig(cfg config.Config) (AIProvider, error) {
-	switch cfg.ResolveProvider() {
-	case ProviderOllama:
-		return NewOllama(cfg.OllamaBaseURL, cfg.OllamaChatModel, cfg.OllamaEmbedModel), nil
-	default:
-		return New(cfg.ResolveProvider(), cfg.APIKey())
+	chat, err := NewChat(cfg)
+	if err != nil {
+		return nil, err
+	}
+	embedder, err := NewEmbedder(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return Combine(chat, embedder), nil
+}
+
+// combined pairs a chat provider with an embedding provider.
+type combined struct {
+	Chat
+	Embedder
+}
+
+// Combine returns a provider that chats with chat and embeds with embedder.
+func Combine(chat Chat, embedder Embedder) AIProvider {
+	return &combined{Chat: chat, Embedder: embedder}
+}
+
+// EmbeddingBatchSize is the embedder's limit.
+func (c *combined) EmbeddingBatchSize() int {
+	return EmbeddingBatchSize(c.Embedder)
+}
+
+func (c *combined) unwrapEmbedder() Embedder {
+	return c.Embedder
+}
+
+// IsLocal reports whether e embeds with the built-in Local provider, looking
+// through Combine and the embedding cache.
+func IsLocal(e Embedder) bool {
+	for {
+		switch v := e.(type) {
+		case *Local:
+			return true
+		case interface{ unwrapEmbedder() Embedder }:
+			e = v.unwrapEmbedder()
+		default:
+			return false
+		}
+	}
+}
+
+func (c *combined) unwrapChat() Chat {
+	return c.Chat
+}
+
+// IsLocalChat reports whether c chats with the built-in Local provider,
+// which has no model and only templates, looking through Combine.
+func IsLocalChat(c Chat) bool {
+	for {
+		switch v := c.(type) {
+		case *Local:
+			return true
+		case interface{ unwrapChat() Chat }:
+			c = v.unwrapChat()
+		default:
+			return false
+		}
 	}
 }
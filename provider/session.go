@@ -0,0 +1,106 @@
+package provider
+
+import (
+	"context"
+	"slices"
+	"strings"
+)
+
+// candidateTemperatures are cycled through when candidates are requested
+// one at a time, so repeated requests do not return the same message.
+var candidateTemperatures = []float32{0.7, 1.0, 0.4}
+
+// CommitSession is the conversation about the commit message for one diff.
+// Suggestions the user gave feedback on and the feedback itself stay in the
+// history, so each refinement builds on everything said before.
+type CommitSession struct {
+	chat     Chat
+	diff     string
+	messages []Message
+}
+
+// NewCommitSession starts a conversation about diff.
+func NewCommitSession(c Chat, diff string) *CommitSession {
+	return &CommitSession{
+		chat:     c,
+		diff:     diff,
+		messages: []Message{{Role: RoleUser, Content: CommitMessageUser + diff}},
+	}
+}
+
+// History returns the conversation so far, starting with the diff prompt.
+func (s *CommitSession) History() []Message {
+	return slices.Clone(s.messages)
+}
+
+// Suggest asks for up to n distinct messages for the conversation so far.
+func (s *CommitSession) Suggest(ctx context.Context, n int) ([]string, error) {
+	if m, ok := s.chat.(CommitMessager); ok {
+		msg, err := m.CommitMessage(s.diff)
+		if err != nil {
+			return nil, err
+		}
+		return []string{msg}, nil
+	}
+	return candidates(ctx, s.chat, Request{
+		System:    CommitMessageSystem,
+		Messages:  s.History(),
+		MaxTokens: 128,
+	}, n)
+}
+
+// Refine tells the model what to change about previous, its last answer
+// as the user saw it, and asks for up to n new messages.
+func (s *CommitSession) Refine(ctx context.Context, previous, feedback string, n int) ([]string, error) {
+	s.messages = append(s.messages,
+		Message{Role: RoleAssistant, Content: previous},
+		Message{Role: RoleUser, Content: feedback + "\n\nReply with the revised commit message only."},
+	)
+	return s.Suggest(ctx, n)
+}
+
+// GenerateCommitMessages asks c for up to n distinct commit messages for
+// diff, see CommitSession for refining them.
+func GenerateCommitMessages(ctx context.Context, c Chat, diff string, n int) ([]string, error) {
+	return NewCommitSession(c, diff).Suggest(ctx, n)
+}
+
+// candidates completes req until it has n distinct answers. Providers that
+// return several choices are asked once, missing ones are topped up with
+// single requests at varied temperatures.
+func candidates(ctx context.Context, c Chat, req Request, n int) ([]string, error) {
+	req.Temperature = candidateTemperatures[0]
+	req.Candidates = n
+
+	var out []string
+	add := func(texts ...string) {
+		for _, t := range texts {
+			t = strings.TrimSpace(t)
+			if t != "" && len(out) < n && !slices.Contains(out, t) {
+				out = append(out, t)
+			}
+		}
+	}
+
+	// a provider rejecting the candidate count still gets single requests
+	if resp, err := c.Complete(ctx, req); err == nil {
+		add(resp.Text)
+		add(resp.Choices...)
+	} else if n <= 1 {
+		return nil, err
+	}
+
+	req.Candidates = 0
+	for i := 1; len(out) < n && i <= 2*n; i++ {
+		req.Temperature = candidateTemperatures[i%len(candidateTemperatures)]
+		resp, err := c.Complete(ctx, req)
+		if err != nil {
+			if len(out) > 0 {
+				break
+			}
+			return nil, err
+		}
+		add(resp.Text)
+	}
+	return out, nil
+}
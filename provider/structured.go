@@ -0,0 +1,223 @@
+package provider
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Validator is implemented by structured responses that need checks the
+// schema cannot express, like every hunk being assigned exactly once.
+type Validator interface {
+	Validate() error
+}
+
+// CompleteJSON asks c for JSON matching the schema of out, a pointer to a
+// struct, and decodes the answer into out. A response that does not decode
+// or validate is sent back to the model with the error, once, before
+// giving up.
+func CompleteJSON(ctx context.Context, c Chat, req Request, out any) error {
+	if req.JSONSchema == nil {
+		schema, err := SchemaFor(out)
+		if err != nil {
+			return err
+		}
+		req.JSONSchema = schema
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < 2; attempt++ {
+		resp, err := c.Complete(ctx, req)
+		if err != nil {
+			return err
+		}
+
+		lastErr = DecodeJSON(resp.Text, req.JSONSchema, out)
+		if lastErr == nil {
+			return nil
+		}
+
+		req.Messages = append(slices.Clip(req.Messages),
+			Message{Role: RoleAssistant, Content: resp.Text},
+			Message{Role: RoleUser, Content: fmt.Sprintf(
+				"That response is invalid: %v. Reply with the corrected JSON only.", lastErr)},
+		)
+	}
+	return fmt.Errorf("invalid structured response: %w", lastErr)
+}
+
+// DecodeJSON decodes the JSON object in raw into out, checks it against
+// schema and runs out's Validate method if it has one. Markdown fences and
+// text around the object are ignored, models without native JSON output
+// add them.
+func DecodeJSON(raw string, schema map[string]any, out any) error {
+	start, end := strings.Index(raw, "{"), strings.LastIndex(raw, "}")
+	if start < 0 || end < start {
+		return errors.New("no JSON object in response")
+	}
+	data := []byte(raw[start : end+1])
+
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return fmt.Errorf("decoding JSON: %w", err)
+	}
+	if schema != nil {
+		if err := checkSchema(schema, value, "response"); err != nil {
+			return err
+		}
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("decoding JSON: %w", err)
+	}
+
+	if v, ok := out.(Validator); ok {
+		return v.Validate()
+	}
+	return nil
+}
+
+// SchemaFor derives a JSON schema from the struct v points to. Fields are
+// named by their json tag and all of them are required, a `desc` tag
+// becomes the description. Objects allow no additional properties, as
+// OpenAI's strict mode demands.
+func SchemaFor(v any) (map[string]any, error) {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil || t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("schema: %T is not a struct", v)
+	}
+	return schemaOf(t)
+}
+
+func schemaOf(t reflect.Type) (map[string]any, error) {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return schemaOf(t.Elem())
+	case reflect.String:
+		return map[string]any{"type": "string"}, nil
+	case reflect.Bool:
+		return map[string]any{"type": "boolean"}, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]any{"type": "integer"}, nil
+	case reflect.Float32, reflect.Float64:
+		return map[string]any{"type": "number"}, nil
+	case reflect.Slice, reflect.Array:
+		items, err := schemaOf(t.Elem())
+		if err != nil {
+			return nil, err
+		}
+		return map[string]any{"type": "array", "items": items}, nil
+	case reflect.Struct:
+		properties := make(map[string]any)
+		var required []string
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+			if !f.IsExported() || name == "-" {
+				continue
+			}
+			if name == "" {
+				name = f.Name
+			}
+
+			s, err := schemaOf(f.Type)
+			if err != nil {
+				return nil, fmt.Errorf("%s.%s: %w", t.Name(), f.Name, err)
+			}
+			if desc := f.Tag.Get("desc"); desc != "" {
+				s["description"] = desc
+			}
+			properties[name] = s
+			required = append(required, name)
+		}
+		return map[string]any{
+			"type":                 "object",
+			"properties":           properties,
+			"required":             required,
+			"additionalProperties": false,
+		}, nil
+	default:
+		return nil, fmt.Errorf("schema: unsupported type %s", t)
+	}
+}
+
+// checkSchema checks the types and required properties of a decoded JSON
+// value, the subset of JSON schema SchemaFor produces.
+func checkSchema(schema map[string]any, value any, path string) error {
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an object", path)
+		}
+		for _, name := range stringList(schema["required"]) {
+			if _, ok := obj[name]; !ok {
+				return fmt.Errorf("%s: missing %q", path, name)
+			}
+		}
+		properties, _ := schema["properties"].(map[string]any)
+		for name, v := range obj {
+			if s, ok := properties[name].(map[string]any); ok {
+				if err := checkSchema(s, v, path+"."+name); err != nil {
+					return err
+				}
+			}
+		}
+	case "array":
+		arr, ok := value.([]any)
+		if !ok {
+			return fmt.Errorf("%s: expected an array", path)
+		}
+		items, _ := schema["items"].(map[string]any)
+		for i, v := range arr {
+			if items == nil {
+				break
+			}
+			if err := checkSchema(items, v, fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return fmt.Errorf("%s: expected a string", path)
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return fmt.Errorf("%s: expected a boolean", path)
+		}
+	case "number":
+		if _, ok := value.(float64); !ok {
+			return fmt.Errorf("%s: expected a number", path)
+		}
+	case "integer":
+		if n, ok := value.(float64); !ok || n != float64(int64(n)) {
+			return fmt.Errorf("%s: expected an integer", path)
+		}
+	}
+	return nil
+}
+
+// stringList reads a schema list that is []string when built by SchemaFor
+// and []any when decoded from JSON.
+func stringList(v any) []string {
+	switch l := v.(type) {
+	case []string:
+		return l
+	case []any:
+		out := make([]string, 0, len(l))
+		for _, s := range l {
+			if s, ok := s.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+// Package semver parses, compares and bumps semantic versions, see
+// https://semver.org/spec/v2.0.0.html. A leading "v", as in git tags, is
+// accepted and kept.
+package semver
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Version is a parsed semantic version.
+type Version struct {
+	Major, Minor, Patch int
+	// Pre holds the dot-separated pre-release identifiers, e.g. ["rc", "1"].
+	Pre []string
+	// Build is the build metadata after "+", ignored when comparing.
+	Build string
+	// V is set when the version was written with a leading "v".
+	V bool
+}
+
+var pattern = regexp.MustCompile(`^(v)?(0|[1-9]\d*)\.(0|[1-9]\d*)\.(0|[1-9]\d*)` +
+	`(?:-((?:0|[1-9]\d*|\d*[A-Za-z-][0-9A-Za-z-]*)(?:\.(?:0|[1-9]\d*|\d*[A-Za-z-][0-9A-Za-z-]*))*))?` +
+	`(?:\+([0-9A-Za-z-]+(?:\.[0-9A-Za-z-]+)*))?$`)
+
+// Parse parses s, e.g. "v1.2.3-rc.1+build.5".
+func Parse(s string) (Version, error) {
+	m := pattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return Version{}, fmt.Errorf("%q is not a semantic version", s)
+	}
+
+	v := Version{V: m[1] == "v", Build: m[6]}
+	for i, p := range []*int{&v.Major, &v.Minor, &v.Patch} {
+		n, err := strconv.Atoi(m[i+2])
+		if err != nil {
+			return Version{}, fmt.Errorf("%q: %w", s, err)
+		}
+		*p = n
+	}
+	if m[5] != "" {
+		v.Pre = strings.Split(m[5], ".")
+	}
+	return v, nil
+}
+
+// String formats v, with the leading "v" if it was parsed with one.
+func (v Version) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.V {
+		s = "v" + s
+	}
+	if len(v.Pre) > 0 {
+		s += "-" + strings.Join(v.Pre, ".")
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// IsPrerelease reports whether v has pre-release identifiers.
+func (v Version) IsPrerelease() bool {
+	return len(v.Pre) > 0
+}
+
+// Compare returns -1, 0 or 1 as a has lower, equal or higher precedence
+// than b. Build metadata and the "v" are ignored.
+func Compare(a, b Version) int {
+	for _, d := range [][2]int{{a.Major, b.Major}, {a.Minor, b.Minor}, {a.Patch, b.Patch}} {
+		if c := compareInt(d[0], d[1]); c != 0 {
+			return c
+		}
+	}
+
+	// a release has higher precedence than its pre-releases
+	switch {
+	case len(a.Pre) == 0 && len(b.Pre) == 0:
+		return 0
+	case len(a.Pre) == 0:
+		return 1
+	case len(b.Pre) == 0:
+		return -1
+	}
+	for i := 0; i < len(a.Pre) && i < len(b.Pre); i++ {
+		if c := compareIdentifier(a.Pre[i], b.Pre[i]); c != 0 {
+			return c
+		}
+	}
+	return compareInt(len(a.Pre), len(b.Pre))
+}
+
+// compareIdentifier compares numeric identifiers numerically and lower
+// than alphanumeric ones, which compare as strings.
+func compareIdentifier(a, b string) int {
+	an, aErr := strconv.Atoi(a)
+	bn, bErr := strconv.Atoi(b)
+	switch {
+	case aErr == nil && bErr == nil:
+		return compareInt(an, bn)
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
+
+func compareInt(a, b int) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Level is how much a version changes.
+type Level int
+
+const (
+	Patch Level = iota
+	Minor
+	Major
+)
+
+func (l Level) String() string {
+	switch l {
+	case Major:
+		return "major"
+	case Minor:
+		return "minor"
+	}
+	return "patch"
+}
+
+// Bump returns the version after v for a change of level. With pre, e.g.
+// "rc", the result is the next pre-release on that channel: 1.2.3 bumped
+// by minor becomes 1.3.0-rc.1, and 1.3.0-rc.1 becomes 1.3.0-rc.2 as long as
+// the change fits the release it leads to. Without pre a pre-release of
+// that kind is released, 1.3.0-rc.2 becomes 1.3.0.
+func (v Version) Bump(level Level, pre string) Version {
+	next := Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch, V: v.V}
+	if !v.IsPrerelease() || !v.covers(level) {
+		switch level {
+		case Major:
+			next.Major, next.Minor, next.Patch = v.Major+1, 0, 0
+		case Minor:
+			next.Minor, next.Patch = v.Minor+1, 0
+		default:
+			next.Patch = v.Patch + 1
+		}
+	}
+
+	if pre == "" {
+		return next
+	}
+	n := 1
+	if next.Major == v.Major && next.Minor == v.Minor && next.Patch == v.Patch && len(v.Pre) == 2 && v.Pre[0] == pre {
+		if last, err := strconv.Atoi(v.Pre[1]); err == nil {
+			n = last + 1
+		}
+	}
+	next.Pre = []string{pre, strconv.Itoa(n)}
+	return next
+}
+
+// covers reports whether the release a pre-release leads to already
+// includes a change of level: 2.0.0-rc.1 covers any change, 1.3.0-rc.1 a
+// minor or patch one, 1.2.4-rc.1 only a patch.
+func (v Version) covers(level Level) bool {
+	switch level {
+	case Major:
+		return v.Minor == 0 && v.Patch == 0
+	case Minor:
+		return v.Patch == 0
+	}
+	return true
+}
+
+// Latest returns the highest of tags that is a semantic version. ok is
+// false when none is.
+func Latest(tags []string) (tag string, v Version, ok bool) {
+	for _, t := range tags {
+		parsed, err := Parse(t)
+		if err != nil {
+			continue
+		}
+		if !ok || Compare(parsed, v) > 0 {
+			tag, v, ok = t, parsed, true
+		}
+	}
+	return tag, v, ok
+}
+
+// LatestRelease is Latest ignoring pre-releases.
+func LatestRelease(tags []string) (tag string, v Version, ok bool) {
+	var releases []string
+	for _, t := range tags {
+		if parsed, err := Parse(t); err == nil && !parsed.IsPrerelease() {
+			releases = append(releases, t)
+		}
+	}
+	return Latest(releases)
+}
@@ -0,0 +1,106 @@
+package semver
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		input string
+		want  Version
+	}{
+		{"1.2.3", Version{Major: 1, Minor: 2, Patch: 3}},
+		{"v0.0.1", Version{Patch: 1, V: true}},
+		{"10.20.30-rc.1", Version{Major: 10, Minor: 20, Patch: 30, Pre: []string{"rc", "1"}}},
+		{"v1.0.0-alpha+build.5", Version{Major: 1, Pre: []string{"alpha"}, Build: "build.5", V: true}},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.input)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.input, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+		if got.String() != tt.input {
+			t.Errorf("String() = %q, want %q", got.String(), tt.input)
+		}
+	}
+
+	for _, bad := range []string{"invalid", "1.2", "01.2.3", "1.2.3-", "1.2.3-01", "v1.2.3.4", "dev"} {
+		if _, err := Parse(bad); err == nil {
+			t.Errorf("Parse(%q): expected an error", bad)
+		}
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// ascending precedence, from the semver spec
+	ordered := []string{
+		"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta",
+		"1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "v1.0.1", "1.1.0", "2.0.0",
+	}
+	for i := range ordered {
+		for j := range ordered {
+			a, _ := Parse(ordered[i])
+			b, _ := Parse(ordered[j])
+			want := compareInt(i, j)
+			if got := Compare(a, b); got != want {
+				t.Errorf("Compare(%s, %s) = %d, want %d", ordered[i], ordered[j], got, want)
+			}
+		}
+	}
+
+	a, _ := Parse("1.0.0+build.1")
+	b, _ := Parse("v1.0.0+build.2")
+	if Compare(a, b) != 0 {
+		t.Error("build metadata and the v prefix must not affect precedence")
+	}
+}
+
+func TestBump(t *testing.T) {
+	tests := []struct {
+		from  string
+		level Level
+		pre   string
+		want  string
+	}{
+		{"v1.2.3", Patch, "", "v1.2.4"},
+		{"v1.2.3", Minor, "", "v1.3.0"},
+		{"v1.2.3", Major, "", "v2.0.0"},
+		{"1.2.3+build", Patch, "", "1.2.4"},
+		{"v1.2.3", Minor, "rc", "v1.3.0-rc.1"},
+		{"v1.3.0-rc.1", Patch, "rc", "v1.3.0-rc.2"},
+		{"v1.3.0-rc.9", Minor, "rc", "v1.3.0-rc.10"},
+		{"v1.3.0-beta.2", Minor, "rc", "v1.3.0-rc.1"},
+		{"v1.3.0-rc.1", Major, "rc", "v2.0.0-rc.1"},
+		{"v1.2.4-rc.1", Minor, "rc", "v1.3.0-rc.1"},
+		{"v1.3.0-rc.2", Minor, "", "v1.3.0"},
+		{"v2.0.0-rc.1", Major, "", "v2.0.0"},
+		{"v1.2.4-rc.1", Minor, "", "v1.3.0"},
+	}
+	for _, tt := range tests {
+		v, err := Parse(tt.from)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := v.Bump(tt.level, tt.pre).String(); got != tt.want {
+			t.Errorf("%s bumped by %s (pre %q) = %s, want %s", tt.from, tt.level, tt.pre, got, tt.want)
+		}
+	}
+}
+
+func TestLatest(t *testing.T) {
+	tag, v, ok := Latest([]string{"v1.9.0", "nightly", "v1.10.0-rc.1", "v1.2.0", "v1.10.0-beta.3"})
+	if !ok || tag != "v1.10.0-rc.1" || v.Minor != 10 {
+		t.Errorf("got %q %+v %v", tag, v, ok)
+	}
+	if tag, _, _ := LatestRelease([]string{"v1.9.0", "v1.10.0-rc.1"}); tag != "v1.9.0" {
+		t.Errorf("latest release = %q", tag)
+	}
+	if _, _, ok := Latest([]string{"nightly"}); ok {
+		t.Error("expected no semver tag")
+	}
+}
@@ -0,0 +1,97 @@
+package split
+
+// agglomerate clusters items with average-linkage agglomerative clustering
+// over a symmetric similarity matrix.
+//
+// Every item starts in its own cluster and the two clusters with the highest
+// average pairwise similarity are merged until either groups clusters remain
+// (when groups > 0) or no pair reaches threshold. Unlike a single greedy pass
+// the result does not depend on the order of the input.
+//
+// Clusters are returned ordered by their first item and each cluster's items
+// are in ascending order, so the output is stable.
+func agglomerate(sim [][]float64, threshold float64, groups int) [][]int {
+	n := len(sim)
+	if n == 0 {
+		return nil
+	}
+
+	clusters := make([][]int, n)
+	for i := range clusters {
+		clusters[i] = []int{i}
+	}
+
+	// link[a][b] caches the average linkage between live clusters a and b
+	link := make([][]float64, n)
+	for i := range link {
+		link[i] = make([]float64, n)
+		copy(link[i], sim[i])
+	}
+	alive := make([]bool, n)
+	for i := range alive {
+		alive[i] = true
+	}
+
+	for remaining := n; remaining > 1; remaining-- {
+		if groups > 0 && remaining <= groups {
+			break
+		}
+
+		bestA, bestB, best := -1, -1, 0.0
+		for a := 0; a < n; a++ {
+			if !alive[a] {
+				continue
+			}
+			for b := a + 1; b < n; b++ {
+				if !alive[b] {
+					continue
+				}
+				if bestA < 0 || link[a][b] > best {
+					bestA, bestB, best = a, b, link[a][b]
+				}
+			}
+		}
+
+		if groups <= 0 && best < threshold {
+			break
+		}
+
+		// Lance-Williams update for average linkage
+		sizeA, sizeB := float64(len(clusters[bestA])), float64(len(clusters[bestB]))
+		for k := 0; k < n; k++ {
+			if !alive[k] || k == bestA || k == bestB {
+				continue
+			}
+			l := (sizeA*link[bestA][k] + sizeB*link[bestB][k]) / (sizeA + sizeB)
+			link[bestA][k], link[k][bestA] = l, l
+		}
+
+		clusters[bestA] = mergeSorted(clusters[bestA], clusters[bestB])
+		clusters[bestB] = nil
+		alive[bestB] = false
+	}
+
+	var out [][]int
+	for i, c := range clusters {
+		if alive[i] {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func mergeSorted(a, b []int) []int {
+	out := make([]int, 0, len(a)+len(b))
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		if a[i] < b[j] {
+			out = append(out, a[i])
+			i++
+		} else {
+			out = append(out, b[j])
+			j++
+		}
+	}
+	out = append(out, a[i:]...)
+	return append(out, b[j:]...)
+}
@@ -1,69 +1,314 @@
 package split
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"os/exec"
+	"os/signal"
 	"path/filepath"
+	"strings"
+	"sync/atomic"
+	"syscall"
+
+	"github.com/ademajagon/gix/internal/git"
 )
 
-// ApplyGroups stashes untracked changes, creates one commit per group then pops the stash
+var errInterrupted = errors.New("interrupted")
+
+// ApplyGroups creates one commit per group, built entirely with plumbing:
+// each group is applied to a private index file, written as a tree and
+// committed with `git commit-tree`. HEAD is only moved once all commits
+// exist, with a single `git update-ref`, and the user's index is then reset
+// to the new HEAD. The working tree, untracked files and stash are never
+// touched, hunks that were not committed are left as unstaged changes.
+//
+// Progress is recorded in a journal, so after a crash the split can be
+// resumed with ContinueSplit or dropped with AbortSplit. A finished split
+// can be reverted with Undo. Commit hooks do not run for split commits.
 func ApplyGroups(groups []HunkGroup) error {
-	if len(groups) == 0 {
+	head := headCommit()
+	return start(&journal{OrigHead: head, Base: head, Groups: groups})
+}
+
+// start records a new split in the journal and runs it.
+func start(j *journal) error {
+	if len(j.Groups) == 0 {
 		return fmt.Errorf("no groups to apply")
 	}
 
-	stash := exec.Command("git", "stash", "push",
-		"--include-untracked",
-		"--keep-index",
-		"--quiet",
-		"-m", "gix-split-temp",
-	)
-	stash.Stderr = os.Stderr
-	if err := stash.Run(); err != nil {
-		return fmt.Errorf("git stash: %w", err)
+	if prev, err := loadJournal(); err == nil && !prev.Finished {
+		return fmt.Errorf("a split is already in progress, run `gix split --continue` or `gix split --abort`")
+	}
+
+	origIndex, err := git.WriteTree()
+	if err != nil {
+		return fmt.Errorf("recording index: %w", err)
 	}
 
-	success := false
-	defer func() {
-		if !success {
-			_ = exec.Command("git", "stash", "pop", "--quiet").Run()
+	j.OrigIndex = origIndex
+	if err := j.save(); err != nil {
+		return err
+	}
+
+	return j.run()
+}
+
+// ContinueSplit resumes a split that was interrupted by a crash.
+func ContinueSplit() error {
+	j, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	if j.Finished {
+		return fmt.Errorf("no split in progress")
+	}
+
+	switch head := headCommit(); {
+	case head == j.OrigHead:
+		return j.run()
+	case head == j.head() && len(j.Commits) == len(j.Groups):
+		// crashed after moving HEAD, only the index is left to update
+		return j.finish()
+	default:
+		return fmt.Errorf("HEAD moved since the split was interrupted, run `gix split --abort`")
+	}
+}
+
+// AbortSplit drops an interrupted split, restoring HEAD and the index if
+// the split got as far as moving them.
+func AbortSplit() error {
+	j, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	if j.Finished {
+		return fmt.Errorf("no split in progress, use `gix undo` to revert the last split")
+	}
+	return j.rollback()
+}
+
+// Undo reverts the last finished split: HEAD and the index go back to where
+// they were before it, the working tree is left alone.
+func Undo() error {
+	j, err := loadJournal()
+	if err != nil {
+		return err
+	}
+	if !j.Finished {
+		return fmt.Errorf("a split is in progress, run `gix split --abort` instead")
+	}
+	if headCommit() != j.newHead() {
+		return fmt.Errorf("HEAD moved since the last split, refusing to undo")
+	}
+	return j.rollback()
+}
+
+// run builds the remaining commits and then publishes them. Nothing the
+// user can see changes until finish, so a failure only needs cleaning up.
+func (j *journal) run() error {
+	interrupted, stop := watchInterrupt()
+	defer stop()
+
+	err := j.buildRemaining(interrupted)
+	if err == nil && j.rewrites() {
+		err = j.checkRewrite()
+	}
+	if err != nil {
+		if rbErr := j.rollback(); rbErr != nil {
+			return fmt.Errorf("%w (cleanup failed: %v, run `gix split --abort` to retry)", err, rbErr)
+		}
+		return err
+	}
+
+	return j.finish()
+}
+
+func (j *journal) buildRemaining(interrupted *atomic.Bool) error {
+	index, err := tempIndex()
+	if err != nil {
+		return err
+	}
+	defer index.Remove()
+
+	if err := index.ReadTree(j.head()); err != nil {
+		return fmt.Errorf("preparing index: %w", err)
+	}
+
+	// replay committed groups so later hunks are re-targeted correctly
+	var builder patchBuilder
+	for _, group := range j.Groups[:len(j.Commits)] {
+		builder.build(group.Hunks)
+	}
+
+	for i := len(j.Commits); i < len(j.Groups); i++ {
+		group := j.Groups[i]
+		if interrupted.Load() {
+			return errInterrupted
+		}
+
+		subject, _ := splitMessage(group.Message)
+		fmt.Printf("[%d/%d] %s\n", i+1, len(j.Groups), subject)
+
+		patch, zeroContext := builder.build(group.Hunks)
+		if err := index.Apply(patch, zeroContext); err != nil {
+			return fmt.Errorf("group %d: %w", i+1, err)
 		}
-	}()
 
-	for i, group := range groups {
-		fmt.Printf("[%d/%d] %s\n", i+1, len(groups), group.Message)
+		tree, err := index.WriteTree()
+		if err != nil {
+			return fmt.Errorf("group %d: %w", i+1, err)
+		}
 
-		if err := exec.Command("git", "reset", "--quiet").Run(); err != nil {
-			return fmt.Errorf("git reset (group %d): %w", i+1, err)
+		commit, err := git.CommitTreeAs(tree, j.head(), j.author(i), group.Message)
+		if err != nil {
+			return fmt.Errorf("group %d: %w", i+1, err)
 		}
 
-		patch := joinPatch(group.Hunks)
-		tmpPath := filepath.Join(os.TempDir(), fmt.Sprintf("gix_split_%d.patch", i))
-		if err := os.WriteFile(tmpPath, []byte(patch), 0o600); err != nil {
-			return fmt.Errorf("writing patch file (group %d): %w", i+1, err)
+		j.Commits = append(j.Commits, commit)
+		if err := j.save(); err != nil {
+			return err
 		}
-		defer os.Remove(tmpPath)
+	}
+
+	return nil
+}
 
-		applyCmd := exec.Command("git", "apply", "--cached", tmpPath)
-		applyCmd.Stderr = os.Stderr
-		if err := applyCmd.Run(); err != nil {
-			return fmt.Errorf("git apply (group %d): %w", i+1, err)
+// finish moves HEAD to the last commit in one step and resets the user's
+// index to it. When existing commits were split, the commits that followed
+// them are rebased instead, and the index is left alone since the final
+// tree does not change.
+func (j *journal) finish() error {
+	head := j.head()
+	switch {
+	case j.rewrites() && j.Tip != j.OrigHead:
+		if err := git.RebaseOnto(head, j.Tip); err != nil {
+			_ = removeJournal()
+			return fmt.Errorf("replaying the commits after %s: %w\n"+
+				"resolve the conflicts and run `git rebase --continue`, or `git rebase --abort` to restore the branch",
+				shortHash(j.Tip), err)
 		}
+		head = headCommit()
+	case headCommit() != head:
+		if err := git.UpdateRef("HEAD", head, j.OrigHead, "gix split"); err != nil {
+			return fmt.Errorf("updating HEAD: %w", err)
+		}
+	}
 
-		commitCmd := exec.Command("git", "commit", "-m", group.Message)
-		commitCmd.Stdout = os.Stdout
-		commitCmd.Stderr = os.Stderr
-		if err := commitCmd.Run(); err != nil {
-			return fmt.Errorf("git commit (group %d): %w", i+1, err)
+	if !j.rewrites() {
+		if err := git.ReadTree(head); err != nil {
+			return fmt.Errorf("updating index: %w", err)
 		}
 	}
 
-	success = true
+	j.NewHead = head
+	j.Finished = true
+	return j.save()
+}
 
-	if err := exec.Command("git", "stash", "pop", "--quiet").Run(); err != nil {
-		fmt.Fprintf(os.Stderr, "warning: git stash pop failed: %v\n", err)
+// rollback restores HEAD and the index to their state before the split if
+// it had moved them, then forgets the journal. Commit objects that were
+// built are left for git gc.
+func (j *journal) rollback() error {
+	if head := headCommit(); head != j.OrigHead && head == j.newHead() {
+		if err := git.UpdateRef("HEAD", j.OrigHead, head, "gix split: rollback"); err != nil {
+			return fmt.Errorf("restoring HEAD: %w", err)
+		}
+		if !j.rewrites() {
+			if err := git.ReadTree(j.OrigIndex); err != nil {
+				return fmt.Errorf("restoring index: %w", err)
+			}
+		}
 	}
 
-	return nil
+	if index, err := tempIndex(); err == nil {
+		_ = index.Remove()
+	}
+	return removeJournal()
+}
+
+// newHead returns where HEAD points once the split is published.
+func (j *journal) newHead() string {
+	if j.NewHead != "" {
+		return j.NewHead
+	}
+	return j.head()
+}
+
+// headCommit returns the commit HEAD points at, or "" on an unborn branch.
+func headCommit() string {
+	head, err := git.ResolveRef("HEAD")
+	if err != nil {
+		return ""
+	}
+	return head
+}
+
+// tempIndex returns the private index file the split is built in.
+func tempIndex() (git.Index, error) {
+	path, err := journalPath()
+	if err != nil {
+		return git.Index{}, err
+	}
+	return git.Index{Path: filepath.Join(filepath.Dir(path), "split-index")}, nil
+}
+
+// watchInterrupt catches Ctrl-C and SIGTERM so a split can clean up
+// instead of dying half way. Child git processes still get the signal and
+// fail, which triggers the cleanup too.
+func watchInterrupt() (*atomic.Bool, func()) {
+	var interrupted atomic.Bool
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, os.Interrupt, syscall.SIGTERM)
+
+	done := make(chan struct{})
+	go func() {
+		select {
+		case <-ch:
+			interrupted.Store(true)
+		case <-done:
+		}
+	}()
+
+	return &interrupted, func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
+
+// patchBuilder re-targets hunks as groups are applied one after another.
+// Hunk headers are relative to the original file, once earlier groups have
+// added or removed lines above a hunk its start must move by the same amount.
+// Context hunks would survive this thanks to git's fuzzy matching, but
+// zero-context hunks (`gix split --granular`) are applied at exact positions.
+type patchBuilder struct {
+	applied map[string][]git.Hunk // by file path, original coordinates
+}
+
+// build returns the patch for the next group and whether it needs
+// --unidiff-zero, then records the hunks as applied.
+func (b *patchBuilder) build(hunks []git.Hunk) (string, bool) {
+	if b.applied == nil {
+		b.applied = make(map[string][]git.Hunk)
+	}
+
+	var sb strings.Builder
+	zeroContext := false
+	for _, h := range hunks {
+		shift := 0
+		for _, prev := range b.applied[h.FilePath] {
+			if prev.OldStart < h.OldStart {
+				shift += prev.Delta()
+			}
+		}
+
+		sb.WriteString(h.WithStart(h.OldStart + shift).Body)
+		sb.WriteString("\n")
+
+		if !h.HasContext() {
+			zeroContext = true
+		}
+		b.applied[h.FilePath] = append(b.applied[h.FilePath], h)
+	}
+
+	return sb.String(), zeroContext
 }
@@ -0,0 +1,406 @@
+package split
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/ademajagon/gix/internal/git"
+)
+
+// newRepo creates a repository with one commit holding a.txt and b.txt in
+// a temp dir, changes into it and isolates git from the user's config.
+func newRepo(t *testing.T) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+
+	dir := t.TempDir()
+	t.Chdir(dir)
+	t.Setenv("HOME", dir)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("GIT_CONFIG_GLOBAL", filepath.Join(dir, ".gitconfig"))
+	t.Setenv("GIT_AUTHOR_NAME", "Ada")
+	t.Setenv("GIT_AUTHOR_EMAIL", "ada@example.com")
+	t.Setenv("GIT_COMMITTER_NAME", "Ada")
+	t.Setenv("GIT_COMMITTER_EMAIL", "ada@example.com")
+
+	gitRun(t, "init", "-q", "-b", "main")
+	writeFile(t, "a.txt", numbered("a", 20))
+	writeFile(t, "b.txt", numbered("b", 20))
+	gitRun(t, "add", ".")
+	gitRun(t, "commit", "-qm", "initial")
+}
+
+func gitRun(t *testing.T, args ...string) string {
+	t.Helper()
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+func writeFile(t *testing.T, name, content string) {
+	t.Helper()
+	if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func numbered(prefix string, n int) string {
+	var sb strings.Builder
+	for i := 1; i <= n; i++ {
+		sb.WriteString(prefix + " line " + strings.Repeat("x", i%3) + "\n")
+	}
+	return sb.String()
+}
+
+// stageChanges edits both files, stages them and returns one group per file.
+func stageChanges(t *testing.T) []HunkGroup {
+	t.Helper()
+	writeFile(t, "a.txt", "a header\n"+numbered("a", 20))
+	writeFile(t, "b.txt", numbered("b", 20)+"b footer\n")
+	gitRun(t, "add", "a.txt", "b.txt")
+
+	hunks, err := git.ParseHunks(1 << 20)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hunks) != 2 {
+		t.Fatalf("expected 2 hunks, got %d", len(hunks))
+	}
+	return []HunkGroup{
+		{Hunks: hunks[:1], Message: "feat: add a header"},
+		{Hunks: hunks[1:], Message: "feat: add b footer"},
+	}
+}
+
+// brokenGroup returns a group whose hunk no longer applies.
+func brokenGroup(g HunkGroup) HunkGroup {
+	h := g.Hunks[0]
+	h.Body = strings.Replace(h.Body, " b line", " b changed", 1)
+	return HunkGroup{Hunks: []git.Hunk{h}, Message: g.Message}
+}
+
+// buildPartial leaves the journal a crash after the first commit would:
+// one of two commits built and HEAD not moved yet.
+func buildPartial(t *testing.T, groups []HunkGroup) *journal {
+	t.Helper()
+	head := headCommit()
+	index, err := git.WriteTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	j := &journal{OrigHead: head, OrigIndex: index, Base: head, Groups: groups[:1]}
+	if err := j.save(); err != nil {
+		t.Fatal(err)
+	}
+	if err := j.buildRemaining(new(atomic.Bool)); err != nil {
+		t.Fatal(err)
+	}
+	j.Groups = groups
+	if err := j.save(); err != nil {
+		t.Fatal(err)
+	}
+	return j
+}
+
+func indexTree(t *testing.T) string {
+	t.Helper()
+	tree, err := git.WriteTree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tree
+}
+
+func TestJournal_SaveAndLoad(t *testing.T) {
+	newRepo(t)
+
+	if _, err := loadJournal(); !errors.Is(err, ErrNoJournal) {
+		t.Fatalf("expected ErrNoJournal, got %v", err)
+	}
+
+	j := &journal{
+		OrigHead:  "1111111",
+		OrigIndex: "2222222",
+		Base:      "1111111",
+		Groups:    []HunkGroup{{Message: "feat: one"}},
+		Commits:   []string{"3333333"},
+	}
+	if err := j.save(); err != nil {
+		t.Fatal(err)
+	}
+	got, err := loadJournal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, j) {
+		t.Errorf("loaded %+v, want %+v", got, j)
+	}
+	if got.head() != "3333333" {
+		t.Errorf("head() = %q", got.head())
+	}
+
+	if err := removeJournal(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loadJournal(); !errors.Is(err, ErrNoJournal) {
+		t.Errorf("expected ErrNoJournal after remove, got %v", err)
+	}
+}
+
+func TestApplyGroups_FailureRollsBack(t *testing.T) {
+	newRepo(t)
+	groups := stageChanges(t)
+	head, index := headCommit(), indexTree(t)
+
+	groups[1] = brokenGroup(groups[1])
+	if err := ApplyGroups(groups); err == nil || !strings.Contains(err.Error(), "group 2") {
+		t.Fatalf("expected group 2 to fail, got %v", err)
+	}
+
+	if headCommit() != head {
+		t.Error("HEAD moved")
+	}
+	if indexTree(t) != index {
+		t.Error("index changed")
+	}
+	if _, err := loadJournal(); !errors.Is(err, ErrNoJournal) {
+		t.Errorf("journal left behind: %v", err)
+	}
+}
+
+func TestContinueSplit(t *testing.T) {
+	newRepo(t)
+	groups := stageChanges(t)
+	head, index := headCommit(), indexTree(t)
+	buildPartial(t, groups)
+
+	if err := ApplyGroups(groups); err == nil {
+		t.Fatal("expected a new split to be refused while one is in progress")
+	}
+	if err := ContinueSplit(); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := gitRun(t, "log", "--format=%s", head+"..HEAD"); got != "feat: add b footer\nfeat: add a header" {
+		t.Errorf("commits = %q", got)
+	}
+	if tree, _ := git.TreeOf("HEAD"); tree != index {
+		t.Error("split commits do not add up to the staged changes")
+	}
+	if indexTree(t) != index {
+		t.Error("index does not match the new HEAD")
+	}
+	if j, err := loadJournal(); err != nil || !j.Finished {
+		t.Errorf("journal not finished: %+v %v", j, err)
+	}
+	if err := ContinueSplit(); err == nil {
+		t.Error("expected continue to fail without a split in progress")
+	}
+}
+
+func TestAbortSplit(t *testing.T) {
+	newRepo(t)
+	groups := stageChanges(t)
+	head, index := headCommit(), indexTree(t)
+
+	t.Run("before HEAD moved", func(t *testing.T) {
+		buildPartial(t, groups)
+		if err := AbortSplit(); err != nil {
+			t.Fatal(err)
+		}
+		if headCommit() != head || indexTree(t) != index {
+			t.Error("HEAD or index changed")
+		}
+		if _, err := loadJournal(); !errors.Is(err, ErrNoJournal) {
+			t.Errorf("journal left behind: %v", err)
+		}
+	})
+
+	t.Run("after HEAD moved", func(t *testing.T) {
+		j := buildPartial(t, groups)
+		if err := j.buildRemaining(new(atomic.Bool)); err != nil {
+			t.Fatal(err)
+		}
+		// crash after publishing, before the journal was marked finished
+		if err := git.UpdateRef("HEAD", j.head(), head, "test"); err != nil {
+			t.Fatal(err)
+		}
+		if err := git.ReadTree(j.head()); err != nil {
+			t.Fatal(err)
+		}
+
+		if err := AbortSplit(); err != nil {
+			t.Fatal(err)
+		}
+		if headCommit() != head {
+			t.Error("HEAD not restored")
+		}
+		if indexTree(t) != index {
+			t.Error("index not restored")
+		}
+	})
+
+	if err := AbortSplit(); !errors.Is(err, ErrNoJournal) {
+		t.Errorf("expected ErrNoJournal, got %v", err)
+	}
+}
+
+func TestInterruptRollsBack(t *testing.T) {
+	newRepo(t)
+	groups := stageChanges(t)
+	head, index := headCommit(), indexTree(t)
+
+	j := buildPartial(t, groups)
+	var interrupted atomic.Bool
+	interrupted.Store(true)
+	if err := j.buildRemaining(&interrupted); !errors.Is(err, errInterrupted) {
+		t.Fatalf("expected errInterrupted, got %v", err)
+	}
+	if len(j.Commits) != 1 {
+		t.Errorf("built %d commits after the interrupt", len(j.Commits))
+	}
+
+	if err := j.rollback(); err != nil {
+		t.Fatal(err)
+	}
+	if headCommit() != head || indexTree(t) != index {
+		t.Error("HEAD or index changed")
+	}
+	if ix, _ := tempIndex(); fileExists(ix.Path) {
+		t.Error("private index left behind")
+	}
+	if _, err := loadJournal(); !errors.Is(err, ErrNoJournal) {
+		t.Errorf("journal left behind: %v", err)
+	}
+}
+
+func TestUndo(t *testing.T) {
+	newRepo(t)
+	groups := stageChanges(t)
+	head, index := headCommit(), indexTree(t)
+
+	if err := ApplyGroups(groups); err != nil {
+		t.Fatal(err)
+	}
+	if err := AbortSplit(); err == nil {
+		t.Error("expected abort to refuse a finished split")
+	}
+	if err := Undo(); err != nil {
+		t.Fatal(err)
+	}
+
+	if headCommit() != head {
+		t.Error("HEAD not restored")
+	}
+	if indexTree(t) != index {
+		t.Error("changes are not staged again")
+	}
+	if err := Undo(); !errors.Is(err, ErrNoJournal) {
+		t.Errorf("expected ErrNoJournal, got %v", err)
+	}
+}
+
+func TestUndo_RefusesAfterHeadMoved(t *testing.T) {
+	newRepo(t)
+	if err := ApplyGroups(stageChanges(t)); err != nil {
+		t.Fatal(err)
+	}
+	gitRun(t, "commit", "-q", "--allow-empty", "-m", "later")
+
+	if err := Undo(); err == nil {
+		t.Error("expected undo to refuse after HEAD moved")
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+func TestApplyGroups_LeavesWorkTreeAlone(t *testing.T) {
+	newRepo(t)
+	writeFile(t, "b.txt", "stashed\n")
+	gitRun(t, "stash", "-q")
+	stash := gitRun(t, "stash", "list")
+
+	groups := stageChanges(t)
+	writeFile(t, "a.txt", "a header\n"+numbered("a", 20)+"a unstaged\n")
+	writeFile(t, "untracked.txt", "untracked\n")
+	files := map[string]string{}
+	for _, name := range []string{"a.txt", "b.txt", "untracked.txt"} {
+		data, err := os.ReadFile(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		files[name] = string(data)
+	}
+	head := headCommit()
+	reflog := gitRun(t, "reflog", "--format=%H %gs", "HEAD")
+
+	// only the first group is committed, the rest stays uncommitted
+	if err := ApplyGroups(groups[:1]); err != nil {
+		t.Fatal(err)
+	}
+
+	for name, want := range files {
+		if data, _ := os.ReadFile(name); string(data) != want {
+			t.Errorf("%s changed", name)
+		}
+	}
+	if got := gitRun(t, "stash", "list"); got != stash {
+		t.Errorf("stash changed: %q", got)
+	}
+	if got := gitRun(t, "reflog", "--format=%H %gs", "HEAD"); got != headCommit()+" gix split\n"+reflog {
+		t.Errorf("HEAD did not move exactly once:\n%s", got)
+	}
+	if parent := gitRun(t, "rev-parse", "HEAD^"); parent != head {
+		t.Errorf("split commit parent = %s, want %s", parent, head)
+	}
+	if got := gitRun(t, "diff", "--cached", "--name-only"); got != "" {
+		t.Errorf("index differs from the new HEAD: %q", got)
+	}
+	if got := gitRun(t, "diff", "--name-only"); got != "a.txt\nb.txt" {
+		t.Errorf("uncommitted changes = %q", got)
+	}
+}
+
+func TestFinish_HeadMovedConcurrently(t *testing.T) {
+	newRepo(t)
+	groups := stageChanges(t)
+	index := indexTree(t)
+
+	j := buildPartial(t, groups)
+	if err := j.buildRemaining(new(atomic.Bool)); err != nil {
+		t.Fatal(err)
+	}
+	// someone else commits before the split is published
+	gitRun(t, "commit", "-q", "--allow-empty", "-m", "concurrent")
+	moved := headCommit()
+
+	if err := j.finish(); err == nil || !strings.Contains(err.Error(), "updating HEAD") {
+		t.Fatalf("expected updating HEAD to fail, got %v", err)
+	}
+	if headCommit() != moved {
+		t.Error("the concurrent commit was overwritten")
+	}
+	if indexTree(t) != index {
+		t.Error("index changed")
+	}
+	if saved, err := loadJournal(); err != nil || saved.Finished {
+		t.Errorf("journal marked finished: %v", err)
+	}
+	if err := ContinueSplit(); err == nil {
+		t.Error("expected continue to refuse after HEAD moved")
+	}
+}
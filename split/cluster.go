@@ -1,74 +1,227 @@
 package split
 
 import (
+	"context"
 	"fmt"
 	"math"
+	"os"
 	"strings"
+	"sync"
+	"sync/atomic"
 
 	"github.com/ademajagon/gix/internal/git"
 	"github.com/ademajagon/gix/provider"
 )
 
 type HunkGroup struct {
-	Hunks   []git.Hunk
-	Message string
+	Hunks   []git.Hunk `json:"hunks"`
+	Message string     `json:"message"`
 }
 
-const similarityThreshold = 0.85
+// DefaultThreshold is the minimum combined similarity for two groups to be
+// merged when no target group count is set.
+const DefaultThreshold = 0.85
+
+// LocalThreshold replaces DefaultThreshold when hunks are embedded with
+// provider.Local. TF-IDF vectors only overlap on shared tokens, so related
+// hunks score far lower than with a trained embedding model.
+const LocalThreshold = 0.6
+
+// DefaultWorkers is how many commit messages are generated at once when
+// Options does not say.
+const DefaultWorkers = 4
+
+// Options tunes how hunks are clustered.
+type Options struct {
+	// Threshold is the minimum average similarity for merging two groups.
+	// Zero means DefaultThreshold, or LocalThreshold when the hunks end up
+	// embedded locally. Ignored when Groups is set.
+	Threshold float64
+	// Groups, if positive, clusters until exactly this many groups remain
+	// (or fewer, when there are fewer hunks).
+	Groups int
+	// Strategy selects how hunks are grouped, see GroupHunks. Empty means
+	// StrategyEmbed.
+	Strategy string
+	// Workers bounds how many commit messages are generated concurrently.
+	// Zero means DefaultWorkers.
+	Workers int
+	// Progress, if set, is called whenever a batch of hunks is embedded or
+	// a message generated. Calls never overlap.
+	Progress func(Progress)
+}
 
-// ClusterHunks uses embedding-based cosine similarity to group hunks and then generate commit messages for each group
-func ClusterHunks(p provider.AIProvider, hunks []git.Hunk) ([]HunkGroup, error) {
+// ClusterHunks groups hunks with average-linkage agglomerative clustering
+// over embedding cosine similarity combined with structural signals, then
+// generates a commit message for each group
+func ClusterHunks(ctx context.Context, p provider.AIProvider, hunks []git.Hunk, opts Options) ([]HunkGroup, error) {
 	if len(hunks) == 0 {
 		return nil, nil
 	}
 
+	t := newTracker(opts.Progress)
+	clusters, err := clusterIndices(p, hunks, opts, t)
+	if err != nil {
+		return nil, err
+	}
+
+	return groupsWithMessages(ctx, p, hunks, clusters, opts, t)
+}
+
+// clusterIndices returns clusters of indices into hunks.
+func clusterIndices(p provider.AIProvider, hunks []git.Hunk, opts Options, t *tracker) ([][]int, error) {
+	embeddings, local, err := embedHunks(p, hunks, t)
+	if err != nil {
+		return nil, err
+	}
+
+	threshold := opts.Threshold
+	switch {
+	case threshold == 0 && local:
+		threshold = LocalThreshold
+	case threshold == 0:
+		threshold = DefaultThreshold
+	}
+
+	return agglomerate(similarityMatrix(hunks, embeddings), threshold, opts.Groups), nil
+}
+
+// embedHunks embeds the hunks in batches no larger than the provider
+// accepts in one request. If the provider fails, e.g. without network, all
+// hunks are embedded again with the offline provider.Local. local reports
+// whether the vectors came from provider.Local.
+func embedHunks(p provider.Embedder, hunks []git.Hunk, t *tracker) (embeddings [][]float32, local bool, err error) {
 	texts := make([]string, len(hunks))
 	for i, h := range hunks {
 		texts[i] = h.FilePath + "\n" + h.Header + "\n" + h.Body
 	}
 
-	embeddings, err := p.GetEmbeddings(texts)
-	if err != nil {
-		return nil, fmt.Errorf("embedding hunks: %w", err)
+	batch := provider.EmbeddingBatchSize(p)
+	t.update(func(s *Progress) { s.Hunks = len(texts) })
+
+	local = provider.IsLocal(p)
+	embeddings = make([][]float32, 0, len(texts))
+	for start := 0; start < len(texts); start += batch {
+		end := min(start+batch, len(texts))
+
+		vecs, err := p.GetEmbeddings(texts[start:end])
+		if err == nil && len(vecs) != end-start {
+			err = fmt.Errorf("embedding count mismatch: got %d, want %d", len(vecs), end-start)
+		}
+		if err != nil {
+			if local {
+				return nil, false, fmt.Errorf("embedding hunks: %w", err)
+			}
+			// vectors from different models cannot be compared, start over
+			fmt.Fprintf(os.Stderr, "\rwarning: embedding hunks %d-%d: %v, falling back to local embeddings\n", start+1, end, err)
+			return embedHunks(provider.NewLocal(), hunks, t)
+		}
+
+		embeddings = append(embeddings, vecs...)
+		t.update(func(s *Progress) { s.Embedded = end })
+	}
+	return embeddings, local, nil
+}
+
+// groupsWithMessages builds one group per cluster and generates its message.
+func groupsWithMessages(ctx context.Context, p provider.AIProvider, hunks []git.Hunk, clusters [][]int, opts Options, t *tracker) ([]HunkGroup, error) {
+	groups := make([]HunkGroup, len(clusters))
+	for i, c := range clusters {
+		for _, idx := range c {
+			groups[i].Hunks = append(groups[i].Hunks, hunks[idx])
+		}
 	}
 
-	if len(embeddings) != len(hunks) {
-		return nil, fmt.Errorf("embedding count mismatch: got %d, want %d", len(embeddings), len(hunks))
+	if err := fillMessages(ctx, p, groups, opts.Workers, t); err != nil {
+		return nil, err
 	}
+	return groups, nil
+}
 
-	used := make([]bool, len(hunks))
-	var groups []HunkGroup
+// FillMessages generates a commit message for every group without one,
+// up to opts.Workers at a time, reporting to opts.Progress.
+func FillMessages(ctx context.Context, p provider.Chat, groups []HunkGroup, opts Options) error {
+	return fillMessages(ctx, p, groups, opts.Workers, newTracker(opts.Progress))
+}
 
-	for i := range hunks {
-		if used[i] {
-			continue
+func fillMessages(ctx context.Context, p provider.Chat, groups []HunkGroup, workers int, t *tracker) error {
+	var todo []int
+	for i := range groups {
+		if groups[i].Message == "" {
+			todo = append(todo, i)
 		}
-		group := []git.Hunk{hunks[i]}
-		used[i] = true
+	}
+	if len(todo) == 0 {
+		return nil
+	}
 
-		for j := i + 1; j < len(hunks); j++ {
-			if used[j] {
-				continue
-			}
-			if cosineSimilarity(embeddings[i], embeddings[j]) >= similarityThreshold {
-				group = append(group, hunks[j])
-				used[j] = true
+	if workers <= 0 {
+		workers = DefaultWorkers
+	}
+	t.update(func(s *Progress) { s.Messages, s.Groups = 0, len(todo) })
+
+	jobs := make(chan int)
+	errs := make([]error, len(groups))
+	var failed atomic.Bool
+	var wg sync.WaitGroup
+
+	for range min(workers, len(todo)) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				if failed.Load() {
+					continue // drain, one failure fails the whole split
+				}
+				msg, err := provider.GenerateCommitMessage(ctx, p, joinPatch(groups[i].Hunks))
+				if err != nil {
+					errs[i] = fmt.Errorf("generating message for group %d: %w", i+1, err)
+					failed.Store(true)
+					continue
+				}
+				groups[i].Message = msg
+				t.update(func(s *Progress) { s.Messages++ })
 			}
-		}
+		}()
+	}
 
-		groups = append(groups, HunkGroup{Hunks: group})
+	for _, i := range todo {
+		jobs <- i
 	}
+	close(jobs)
+	wg.Wait()
 
-	for i := range groups {
-		patch := joinPatch(groups[i].Hunks)
-		msg, err := p.GenerateCommitMessage(patch)
+	for _, err := range errs {
 		if err != nil {
-			return nil, fmt.Errorf("generating message for group %d: %w", i+1, err)
+			return err
 		}
-		groups[i].Message = msg
 	}
+	return nil
+}
 
-	return groups, nil
+// similarityMatrix combines pairwise embedding similarity with the
+// structural relationship between hunks.
+func similarityMatrix(hunks []git.Hunk, embeddings [][]float32) [][]float64 {
+	facts := make([]hunkFacts, len(hunks))
+	for i, h := range hunks {
+		facts[i] = extractFacts(h)
+	}
+
+	sim := make([][]float64, len(hunks))
+	for i := range sim {
+		sim[i] = make([]float64, len(hunks))
+	}
+	for i := range hunks {
+		sim[i][i] = 1
+		for j := i + 1; j < len(hunks); j++ {
+			s := combineSimilarity(
+				cosineSimilarity(embeddings[i], embeddings[j]),
+				structuralSimilarity(facts[i], facts[j]),
+			)
+			sim[i][j], sim[j][i] = s, s
+		}
+	}
+	return sim
 }
 
 func joinPatch(hunks []git.Hunk) string {
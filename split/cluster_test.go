@@ -0,0 +1,291 @@
+package split
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/ademajagon/gix/internal/git"
+	"github.com/ademajagon/gix/provider"
+)
+
+type fakeProvider struct {
+	embeddings map[string][]float32 // by file path
+	plan       string
+	batch      int // EmbeddingBatchSize, 0 for the provider default
+	embedErr   error
+
+	mu       sync.Mutex
+	messages int
+	batches  []int // size of every GetEmbeddings call
+}
+
+func (f *fakeProvider) EmbeddingBatchSize() int {
+	return f.batch
+}
+
+// Complete answers split plan prompts with plan and anything else with a
+// numbered commit message.
+func (f *fakeProvider) Complete(_ context.Context, req provider.Request) (*provider.Response, error) {
+	if req.System == provider.SplitPlanSystem {
+		return &provider.Response{Text: f.plan}, nil
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.messages++
+	return &provider.Response{Text: fmt.Sprintf("chore: group %d", f.messages)}, nil
+}
+
+func (f *fakeProvider) GetEmbeddings(texts []string) ([][]float32, error) {
+	f.batches = append(f.batches, len(texts))
+	if f.embedErr != nil {
+		return nil, f.embedErr
+	}
+	out := make([][]float32, len(texts))
+	for i, t := range texts {
+		for path, e := range f.embeddings {
+			if len(t) >= len(path) && t[:len(path)] == path {
+				out[i] = e
+			}
+		}
+	}
+	return out, nil
+}
+
+func testHunk(path, header string, lines ...string) git.Hunk {
+	h := git.Hunk{FilePath: path, Header: header}
+	h.Body = header
+	for _, l := range lines {
+		h.Body += "\n" + l
+	}
+	return h
+}
+
+func TestAgglomerate_Threshold(t *testing.T) {
+	sim := [][]float64{
+		{1, 0.9, 0.1, 0.1},
+		{0.9, 1, 0.2, 0.1},
+		{0.1, 0.2, 1, 0.95},
+		{0.1, 0.1, 0.95, 1},
+	}
+
+	got := agglomerate(sim, 0.8, 0)
+	want := [][]int{{0, 1}, {2, 3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("agglomerate() = %v, want %v", got, want)
+	}
+}
+
+func TestAgglomerate_AverageLinkageIsNotChained(t *testing.T) {
+	// 0~1 and 1~2 are similar but 0 and 2 are not: single-pass seeding from 0
+	// would pull in 1 and leave 2 alone, average linkage keeps 0 apart from
+	// the tighter 1-2 pair instead
+	sim := [][]float64{
+		{1, 0.86, 0.1},
+		{0.86, 1, 0.95},
+		{0.1, 0.95, 1},
+	}
+
+	got := agglomerate(sim, 0.85, 0)
+	want := [][]int{{0}, {1, 2}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("agglomerate() = %v, want %v", got, want)
+	}
+}
+
+func TestAgglomerate_TargetGroups(t *testing.T) {
+	sim := [][]float64{
+		{1, 0.1, 0.1, 0.1},
+		{0.1, 1, 0.3, 0.1},
+		{0.1, 0.3, 1, 0.1},
+		{0.1, 0.1, 0.1, 1},
+	}
+
+	got := agglomerate(sim, 0.99, 3)
+	want := [][]int{{0}, {1, 2}, {3}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("agglomerate() = %v, want %v", got, want)
+	}
+
+	if got := agglomerate(sim, 0.99, 1); len(got) != 1 || len(got[0]) != 4 {
+		t.Errorf("expected a single group of 4, got %v", got)
+	}
+	if got := agglomerate(sim, 0.99, 10); len(got) != 4 {
+		t.Errorf("expected 4 singleton groups when target exceeds hunks, got %v", got)
+	}
+}
+
+func TestStructuralSimilarity(t *testing.T) {
+	def := extractFacts(testHunk("provider/registry.go", "@@ -1 +1 @@",
+		"+func NewFromConfig(cfg config.Config) (AIProvider, error) {"))
+	use := extractFacts(testHunk("cmd/commit.go", "@@ -1 +1 @@",
+		"+\tp, err := provider.NewFromConfig(cfg)"))
+	imp := extractFacts(testHunk("cmd/split.go", "@@ -1 +1 @@",
+		`+	"github.com/ademajagon/gix/provider"`))
+	sameFile := extractFacts(testHunk("provider/registry.go", "@@ -40 +40 @@", "+// unrelated"))
+	samePkg := extractFacts(testHunk("provider/openai.go", "@@ -1 +1 @@", "+// unrelated"))
+	other := extractFacts(testHunk("README.md", "@@ -1 +1 @@", "+docs"))
+
+	cases := []struct {
+		name string
+		a, b hunkFacts
+		want float64
+	}{
+		{"symbol reference", def, use, weightSymbolRef},
+		{"import", imp, def, weightImport},
+		{"same file", def, sameFile, weightSameFile},
+		{"same package", def, samePkg, weightSamePackage},
+		{"unrelated", def, other, 0},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := structuralSimilarity(c.a, c.b)
+			if diff := got - c.want; diff > 1e-9 || diff < -1e-9 {
+				t.Errorf("structuralSimilarity() = %v, want %v", got, c.want)
+			}
+			if rev := structuralSimilarity(c.b, c.a); rev != got {
+				t.Errorf("structuralSimilarity is not symmetric: %v vs %v", got, rev)
+			}
+		})
+	}
+}
+
+func TestCombineSimilarity(t *testing.T) {
+	if got := combineSimilarity(0.8, 0); got != 0.8 {
+		t.Errorf("expected unchanged similarity without structure, got %v", got)
+	}
+	if got := combineSimilarity(0.5, 0.5); got != 0.75 {
+		t.Errorf("combineSimilarity(0.5, 0.5) = %v, want 0.75", got)
+	}
+	if got := combineSimilarity(-0.4, 0.5); got != 0.5 {
+		t.Errorf("expected negative cosine to be clamped, got %v", got)
+	}
+}
+
+func TestClusterHunks(t *testing.T) {
+	p := &fakeProvider{embeddings: map[string][]float32{
+		"a.go":      {1, 0, 0},
+		"b.go":      {0.99, 0.1, 0},
+		"README.md": {0, 0, 1},
+	}}
+	hunks := []git.Hunk{
+		testHunk("a.go", "@@ -1 +1 @@", "+x"),
+		testHunk("README.md", "@@ -1 +1 @@", "+docs"),
+		testHunk("b.go", "@@ -1 +1 @@", "+y"),
+	}
+
+	groups, err := ClusterHunks(context.Background(), p, hunks, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if len(groups[0].Hunks) != 2 || groups[0].Hunks[1].FilePath != "b.go" {
+		t.Errorf("expected a.go and b.go together, got %+v", groups[0].Hunks)
+	}
+	if groups[0].Message == "" || groups[1].Message == "" {
+		t.Error("expected a message for every group")
+	}
+}
+
+func TestClusterHunks_BatchesAndReportsProgress(t *testing.T) {
+	p := &fakeProvider{batch: 2, embeddings: map[string][]float32{
+		"a.go": {1, 0},
+		"b.go": {0, 1},
+	}}
+	hunks := []git.Hunk{
+		testHunk("a.go", "@@ -1 +1 @@", "+1"),
+		testHunk("b.go", "@@ -1 +1 @@", "+2"),
+		testHunk("a.go", "@@ -5 +5 @@", "+3"),
+		testHunk("b.go", "@@ -5 +5 @@", "+4"),
+		testHunk("a.go", "@@ -9 +9 @@", "+5"),
+	}
+
+	var reports []string
+	groups, err := ClusterHunks(context.Background(), p, hunks, Options{
+		Workers:  2,
+		Progress: func(pr Progress) { reports = append(reports, pr.String()) },
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if !reflect.DeepEqual(p.batches, []int{2, 2, 1}) {
+		t.Errorf("expected batches of [2 2 1], got %v", p.batches)
+	}
+	if p.messages != len(groups) {
+		t.Errorf("expected %d messages, generated %d", len(groups), p.messages)
+	}
+	want := fmt.Sprintf("embedding 5/5 hunks, messages %d/%d", len(groups), len(groups))
+	if len(reports) == 0 || reports[len(reports)-1] != want {
+		t.Errorf("expected last progress %q, got %q", want, reports)
+	}
+}
+
+func TestFillMessages_KeepsExistingMessages(t *testing.T) {
+	p := &fakeProvider{}
+	groups := []HunkGroup{
+		{Message: "feat: keep me"},
+		{Hunks: []git.Hunk{testHunk("a.go", "@@ -1 +1 @@", "+x")}},
+		{Hunks: []git.Hunk{testHunk("b.go", "@@ -1 +1 @@", "+y")}},
+	}
+
+	if err := FillMessages(context.Background(), p, groups, Options{Workers: 8}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if groups[0].Message != "feat: keep me" {
+		t.Errorf("expected the existing message to be kept, got %q", groups[0].Message)
+	}
+	if groups[1].Message == "" || groups[2].Message == "" || p.messages != 2 {
+		t.Errorf("expected two generated messages, got %+v (%d calls)", groups, p.messages)
+	}
+}
+
+func TestClusterHunks_Offline(t *testing.T) {
+	hunks := []git.Hunk{
+		testHunk("split/cluster.go", "@@ -1 +1 @@", "+func embedHunks(hunks []git.Hunk) {}"),
+		testHunk("README.md", "@@ -1 +1 @@", "+Install gix with brew"),
+		testHunk("split/llm.go", "@@ -1 +1 @@", "+\tvecs := embedHunks(hunks)"),
+	}
+
+	for i, h := range hunks {
+		hunks[i].FileHeader = fmt.Sprintf("diff --git a/%s b/%s", h.FilePath, h.FilePath)
+		hunks[i].Body = hunks[i].FileHeader + "\n" + h.Body
+	}
+
+	groups, err := ClusterHunks(context.Background(), provider.NewLocal(), hunks, Options{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if len(groups[0].Hunks) != 2 || groups[0].Hunks[1].FilePath != "split/llm.go" {
+		t.Errorf("expected the split hunks together, got %+v", groups[0].Hunks)
+	}
+	if groups[0].Message != "chore(split): update cluster.go and llm.go" {
+		t.Errorf("unexpected templated message %q", groups[0].Message)
+	}
+}
+
+func TestClusterHunks_FallsBackToLocalEmbeddings(t *testing.T) {
+	p := &fakeProvider{embedErr: errors.New("network is unreachable")}
+	hunks := []git.Hunk{
+		testHunk("a.go", "@@ -1 +1 @@", "+x"),
+		testHunk("b.go", "@@ -1 +1 @@", "+y"),
+	}
+
+	groups, err := ClusterHunks(context.Background(), p, hunks, Options{})
+	if err != nil {
+		t.Fatalf("expected local fallback, got error: %v", err)
+	}
+	if len(groups) == 0 || p.messages != len(groups) {
+		t.Errorf("expected messages from the chat provider, got %+v", groups)
+	}
+}
@@ -0,0 +1,126 @@
+package split
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/ademajagon/gix/internal/git"
+)
+
+// ErrNoJournal is returned when there is no split to continue, abort or undo.
+var ErrNoJournal = errors.New("no gix split recorded in this repository")
+
+// journal records a split so it can be continued or aborted after a crash,
+// and undone after it finished. It lives in .git/gix/split.json.
+type journal struct {
+	OrigHead  string      `json:"orig_head"`  // empty on an unborn branch
+	OrigIndex string      `json:"orig_index"` // tree of the index before the split
+	Groups    []HunkGroup `json:"groups"`
+	Commits   []string    `json:"commits"` // commits built so far, in order
+	Finished  bool        `json:"finished"`
+
+	// Base is the commit the split commits are built on, OrigHead when
+	// splitting the index. Tip is set when existing commits are split: the
+	// commits in Base..Tip are replaced and those after Tip replayed.
+	Base string `json:"base"`
+	Tip  string `json:"tip,omitempty"`
+	// Authors holds, per group, the replaced commit whose author and date
+	// the new commit keeps. Only set with Tip.
+	Authors []string `json:"authors,omitempty"`
+
+	// NewHead is where HEAD ended up once the split finished.
+	NewHead string `json:"new_head,omitempty"`
+}
+
+func journalPath() (string, error) {
+	dir, err := git.Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "gix", "split.json"), nil
+}
+
+func loadJournal() (*journal, error) {
+	path, err := journalPath()
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, ErrNoJournal
+		}
+		return nil, fmt.Errorf("reading split journal: %w", err)
+	}
+
+	var j journal
+	if err := json.Unmarshal(data, &j); err != nil {
+		return nil, fmt.Errorf("parsing split journal: %w", err)
+	}
+	return &j, nil
+}
+
+// save writes the journal atomically, a crash never leaves a partial file.
+func (j *journal) save() error {
+	path, err := journalPath()
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("creating journal dir: %w", err)
+	}
+
+	data, err := json.MarshalIndent(j, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshalling split journal: %w", err)
+	}
+
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("writing split journal: %w", err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("saving split journal: %w", err)
+	}
+	return nil
+}
+
+func removeJournal() error {
+	path, err := journalPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("removing split journal: %w", err)
+	}
+	return nil
+}
+
+// head returns the last commit built so far, Base if there is none yet.
+func (j *journal) head() string {
+	if len(j.Commits) == 0 {
+		return j.Base
+	}
+	return j.Commits[len(j.Commits)-1]
+}
+
+// author returns the commit group i takes its author from, "" for the
+// current identity.
+func (j *journal) author(i int) string {
+	if i < len(j.Authors) {
+		return j.Authors[i]
+	}
+	return ""
+}
+
+// rewrites reports whether the split replaces existing commits rather than
+// committing the index.
+func (j *journal) rewrites() bool {
+	return j.Tip != ""
+}
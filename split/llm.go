@@ -0,0 +1,156 @@
+package split
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ademajagon/gix/internal/git"
+	"github.com/ademajagon/gix/provider"
+)
+
+// Grouping strategies for `gix split --strategy`.
+const (
+	// StrategyEmbed clusters hunks by embedding similarity, see ClusterHunks.
+	StrategyEmbed = "embed"
+	// StrategyLLM lets the chat model group hunks and write the messages.
+	StrategyLLM = "llm"
+	// StrategyHybrid clusters by embeddings first and hands the clusters to
+	// the chat model as a suggestion it may refine.
+	StrategyHybrid = "hybrid"
+)
+
+// maxSummaryLines caps how many changed lines of each hunk are shown to the
+// chat model, the plan only needs enough to understand what a hunk does.
+const maxSummaryLines = 12
+
+// GroupHunks groups hunks into commits using the strategy in opts. The llm
+// and hybrid strategies fall back to embedding clusters when the chat model
+// does not return a valid plan.
+func GroupHunks(ctx context.Context, p provider.AIProvider, hunks []git.Hunk, opts Options) ([]HunkGroup, error) {
+	t := newTracker(opts.Progress)
+	switch opts.Strategy {
+	case "", StrategyEmbed:
+		return ClusterHunks(ctx, p, hunks, opts)
+	case StrategyLLM:
+		groups, err := planWithLLM(ctx, p, hunks, nil, opts, t)
+		if err == nil {
+			return groups, nil
+		}
+		fmt.Fprintf(os.Stderr, "warning: %v, falling back to embedding clusters\n", err)
+		return ClusterHunks(ctx, p, hunks, opts)
+	case StrategyHybrid:
+		clusters, err := clusterIndices(p, hunks, opts, t)
+		if err != nil {
+			return nil, err
+		}
+		groups, err := planWithLLM(ctx, p, hunks, clusters, opts, t)
+		if err == nil {
+			return groups, nil
+		}
+		fmt.Fprintf(os.Stderr, "warning: %v, falling back to embedding clusters\n", err)
+		return groupsWithMessages(ctx, p, hunks, clusters, opts, t)
+	default:
+		return nil, fmt.Errorf("unknown strategy %q (supported: embed, llm, hybrid)", opts.Strategy)
+	}
+}
+
+// splitPlan is the JSON the chat model is asked to return. Hunk numbers
+// are 1-based as shown in the prompt.
+type splitPlan struct {
+	Commits []struct {
+		Hunks   []int  `json:"hunks" desc:"hunk numbers, in the order shown"`
+		Message string `json:"message" desc:"conventional commit message"`
+	} `json:"commits"`
+
+	hunks int // how many hunks the plan must cover
+}
+
+func planWithLLM(ctx context.Context, p provider.Chat, hunks []git.Hunk, suggestion [][]int, opts Options, t *tracker) ([]HunkGroup, error) {
+	plan := &splitPlan{hunks: len(hunks)}
+	if err := provider.GenerateSplitPlan(ctx, p, summarizeHunks(hunks, suggestion), plan); err != nil {
+		return nil, fmt.Errorf("generating split plan: %w", err)
+	}
+
+	groups := make([]HunkGroup, len(plan.Commits))
+	for i, c := range plan.Commits {
+		for _, id := range c.Hunks {
+			groups[i].Hunks = append(groups[i].Hunks, hunks[id-1])
+		}
+		groups[i].Message = c.Message
+	}
+
+	if err := fillMessages(ctx, p, groups, opts.Workers, t); err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+// summarizeHunks renders the numbered hunk list for the chat model, with the
+// embedding clusters as a hint when the hybrid strategy provides them.
+func summarizeHunks(hunks []git.Hunk, suggestion [][]int) string {
+	var b strings.Builder
+	for i, h := range hunks {
+		fmt.Fprintf(&b, "[%d] %s %s\n", i+1, h.FilePath, h.Header)
+
+		shown := 0
+		for _, l := range h.Lines() {
+			if !strings.HasPrefix(l, "+") && !strings.HasPrefix(l, "-") {
+				continue
+			}
+			if shown == maxSummaryLines {
+				b.WriteString("...\n")
+				break
+			}
+			b.WriteString(l)
+			b.WriteString("\n")
+			shown++
+		}
+		b.WriteString("\n")
+	}
+
+	if len(suggestion) > 0 {
+		b.WriteString("Suggested grouping by textual similarity, keep it where it makes sense:\n")
+		for _, c := range suggestion {
+			ids := make([]string, len(c))
+			for i, idx := range c {
+				ids[i] = fmt.Sprint(idx + 1)
+			}
+			fmt.Fprintf(&b, "- [%s]\n", strings.Join(ids, ", "))
+		}
+	}
+
+	return b.String()
+}
+
+// Validate checks that every hunk is assigned to exactly one
+// non-empty commit, and trims the messages.
+func (plan *splitPlan) Validate() error {
+	if len(plan.Commits) == 0 {
+		return fmt.Errorf("no commits in plan")
+	}
+
+	seen := make([]bool, plan.hunks+1)
+	for i, c := range plan.Commits {
+		if len(c.Hunks) == 0 {
+			return fmt.Errorf("commit %d has no hunks", i+1)
+		}
+		for _, id := range c.Hunks {
+			if id < 1 || id > plan.hunks {
+				return fmt.Errorf("commit %d references unknown hunk %d", i+1, id)
+			}
+			if seen[id] {
+				return fmt.Errorf("hunk %d is assigned more than once", id)
+			}
+			seen[id] = true
+		}
+		plan.Commits[i].Message = strings.TrimSpace(plan.Commits[i].Message)
+	}
+	for id := 1; id <= plan.hunks; id++ {
+		if !seen[id] {
+			return fmt.Errorf("hunk %d is not assigned to any commit", id)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package split
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ademajagon/gix/internal/git"
+	"github.com/ademajagon/gix/provider"
+)
+
+// parsePlan decodes raw the way GenerateSplitPlan decodes a response.
+func parsePlan(raw string, n int) (*splitPlan, error) {
+	plan := &splitPlan{hunks: n}
+	schema, err := provider.SchemaFor(plan)
+	if err != nil {
+		return nil, err
+	}
+	return plan, provider.DecodeJSON(raw, schema, plan)
+}
+
+func TestParsePlan_Valid(t *testing.T) {
+	raw := "```json\n" + `{"commits": [{"hunks": [2, 1], "message": " feat: a "}, {"hunks": [3], "message": "docs: b"}]}` + "\n```"
+
+	plan, err := parsePlan(raw, 3)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(plan.Commits) != 2 {
+		t.Fatalf("expected 2 commits, got %d", len(plan.Commits))
+	}
+	if plan.Commits[0].Message != "feat: a" {
+		t.Errorf("expected trimmed message, got %q", plan.Commits[0].Message)
+	}
+}
+
+func TestParsePlan_Invalid(t *testing.T) {
+	cases := []struct {
+		name, raw string
+	}{
+		{"not json", "sure, here are your commits"},
+		{"malformed", `{"commits": [{"hunks": [1, 2}]}`},
+		{"missing message", `{"commits": [{"hunks": [1, 2]}]}`},
+		{"wrong type", `{"commits": [{"hunks": ["1", "2"], "message": "x"}]}`},
+		{"no commits", `{"commits": []}`},
+		{"empty commit", `{"commits": [{"hunks": [], "message": "x"}, {"hunks": [1, 2], "message": "y"}]}`},
+		{"unknown hunk", `{"commits": [{"hunks": [1, 2, 3], "message": "x"}]}`},
+		{"duplicate hunk", `{"commits": [{"hunks": [1, 2], "message": "x"}, {"hunks": [2], "message": "y"}]}`},
+		{"missing hunk", `{"commits": [{"hunks": [1], "message": "x"}]}`},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			if _, err := parsePlan(c.raw, 2); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
+
+func TestSummarizeHunks(t *testing.T) {
+	hunks := []git.Hunk{
+		testHunk("a.go", "@@ -1 +1 @@", " ctx", "-old", "+new"),
+		testHunk("b.go", "@@ -1 +1 @@", "+b"),
+	}
+
+	got := summarizeHunks(hunks, [][]int{{0, 1}})
+	for _, want := range []string{"[1] a.go @@ -1 +1 @@\n-old\n+new\n", "[2] b.go", "- [1, 2]"} {
+		if !strings.Contains(got, want) {
+			t.Errorf("summary missing %q:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "ctx") {
+		t.Error("expected context lines to be left out of the summary")
+	}
+}
+
+func TestGroupHunks_LLM(t *testing.T) {
+	p := &fakeProvider{plan: `{"commits": [{"hunks": [2], "message": "docs: readme"}, {"hunks": [1, 3], "message": ""}]}`}
+	hunks := []git.Hunk{
+		testHunk("a.go", "@@ -1 +1 @@", "+x"),
+		testHunk("README.md", "@@ -1 +1 @@", "+docs"),
+		testHunk("b.go", "@@ -1 +1 @@", "+y"),
+	}
+
+	groups, err := GroupHunks(context.Background(), p, hunks, Options{Strategy: StrategyLLM})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].Message != "docs: readme" || groups[0].Hunks[0].FilePath != "README.md" {
+		t.Errorf("unexpected first group: %+v", groups[0])
+	}
+	if groups[1].Message == "" {
+		t.Error("expected a generated message for a group the plan left empty")
+	}
+}
+
+func TestGroupHunks_FallsBackOnMalformedPlan(t *testing.T) {
+	for _, strategy := range []string{StrategyLLM, StrategyHybrid} {
+		t.Run(strategy, func(t *testing.T) {
+			p := &fakeProvider{
+				plan: "not json",
+				embeddings: map[string][]float32{
+					"a.go": {1, 0},
+					"b.go": {0, 1},
+				},
+			}
+			hunks := []git.Hunk{
+				testHunk("a.go", "@@ -1 +1 @@", "+x"),
+				testHunk("b.go", "@@ -1 +1 @@", "+y"),
+			}
+
+			groups, err := GroupHunks(context.Background(), p, hunks, Options{Strategy: strategy})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if len(groups) != 2 {
+				t.Errorf("expected embedding fallback with 2 groups, got %d", len(groups))
+			}
+		})
+	}
+}
+
+func TestGroupHunks_UnknownStrategy(t *testing.T) {
+	if _, err := GroupHunks(context.Background(), &fakeProvider{}, nil, Options{Strategy: "magic"}); err == nil {
+		t.Fatal("expected error for unknown strategy, got nil")
+	}
+}
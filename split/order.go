@@ -0,0 +1,123 @@
+package split
+
+import (
+	"strings"
+)
+
+// OrderGroups reorders groups so that a commit never depends on one that
+// comes after it: symbols are defined before they are used, go.mod requires
+// land before code importing the module, and new packages before their
+// importers. Otherwise the original order is kept. Groups in a dependency
+// cycle keep their relative order, --verify is the safety net for those.
+func OrderGroups(groups []HunkGroup) []HunkGroup {
+	n := len(groups)
+	if n < 2 {
+		return groups
+	}
+
+	facts := make([]groupFacts, n)
+	for i, g := range groups {
+		facts[i] = collectGroupFacts(g)
+	}
+
+	// deps[i][j]: group i needs group j applied first
+	deps := make([][]bool, n)
+	pending := make([]int, n)
+	for i := range groups {
+		deps[i] = make([]bool, n)
+		for j := range groups {
+			if i != j && facts[i].dependsOn(facts[j]) {
+				deps[i][j] = true
+				pending[i]++
+			}
+		}
+	}
+
+	done := make([]bool, n)
+	order := make([]HunkGroup, 0, n)
+	for len(order) < n {
+		next := -1
+		for i := range groups {
+			if !done[i] && pending[i] == 0 {
+				next = i
+				break
+			}
+		}
+		if next < 0 {
+			// cycle: release the earliest remaining group
+			for i := range groups {
+				if !done[i] {
+					next = i
+					break
+				}
+			}
+		}
+
+		done[next] = true
+		order = append(order, groups[next])
+		for i := range groups {
+			if deps[i][next] {
+				deps[i][next] = false
+				pending[i]--
+			}
+		}
+	}
+
+	return order
+}
+
+// groupFacts merges the hunk facts of a group.
+type groupFacts struct {
+	defines map[string]bool
+	idents  map[string]bool
+	imports []string
+	modules []string
+	newDirs map[string]bool
+}
+
+func collectGroupFacts(g HunkGroup) groupFacts {
+	gf := groupFacts{
+		defines: make(map[string]bool),
+		idents:  make(map[string]bool),
+		newDirs: make(map[string]bool),
+	}
+	for _, h := range g.Hunks {
+		f := extractFacts(h)
+		for s := range f.added.defines {
+			gf.defines[s] = true
+		}
+		for s := range f.added.idents {
+			gf.idents[s] = true
+		}
+		gf.imports = append(gf.imports, f.added.imports...)
+		gf.modules = append(gf.modules, f.added.modules...)
+		if f.newFile {
+			gf.newDirs[f.dir] = true
+		}
+	}
+	return gf
+}
+
+func (g groupFacts) dependsOn(other groupFacts) bool {
+	for sym := range other.defines {
+		if g.idents[sym] && !g.defines[sym] {
+			return true
+		}
+	}
+
+	for _, imp := range g.imports {
+		for _, mod := range other.modules {
+			if imp == mod || strings.HasPrefix(imp, mod+"/") {
+				return true
+			}
+		}
+	}
+
+	for dir := range other.newDirs {
+		if importsDir(g.imports, dir) {
+			return true
+		}
+	}
+
+	return false
+}
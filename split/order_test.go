@@ -0,0 +1,96 @@
+package split
+
+import (
+	"testing"
+
+	"github.com/ademajagon/gix/internal/git"
+)
+
+func messages(groups []HunkGroup) []string {
+	out := make([]string, len(groups))
+	for i, g := range groups {
+		out[i] = g.Message
+	}
+	return out
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestOrderGroups_DefinitionBeforeUse(t *testing.T) {
+	groups := []HunkGroup{
+		{Message: "use", Hunks: []git.Hunk{testHunk("cmd/commit.go", "@@ -1 +1 @@", "+\tsplit.OrderGroups(groups)")}},
+		{Message: "docs", Hunks: []git.Hunk{testHunk("README.md", "@@ -1 +1 @@", "+docs")}},
+		{Message: "define", Hunks: []git.Hunk{testHunk("split/order.go", "@@ -1 +1 @@", "+func OrderGroups(groups []HunkGroup) []HunkGroup {")}},
+	}
+
+	got := messages(OrderGroups(groups))
+	want := []string{"docs", "define", "use"}
+	if !equalStrings(got, want) {
+		t.Errorf("OrderGroups() = %v, want %v", got, want)
+	}
+}
+
+func TestOrderGroups_GoModBeforeImport(t *testing.T) {
+	groups := []HunkGroup{
+		{Message: "import", Hunks: []git.Hunk{testHunk("cmd/root.go", "@@ -1 +1 @@", `+	"github.com/spf13/cobra/doc"`)}},
+		{Message: "require", Hunks: []git.Hunk{testHunk("go.mod", "@@ -1 +1 @@", "+\tgithub.com/spf13/cobra v1.10.2")}},
+	}
+
+	got := messages(OrderGroups(groups))
+	want := []string{"require", "import"}
+	if !equalStrings(got, want) {
+		t.Errorf("OrderGroups() = %v, want %v", got, want)
+	}
+}
+
+func TestOrderGroups_NewPackageBeforeImporter(t *testing.T) {
+	newFile := testHunk("internal/semver/semver.go", "@@ -0,0 +1 @@", "+package semver")
+	newFile.FileHeader = "diff --git a/internal/semver/semver.go b/internal/semver/semver.go\nnew file mode 100644"
+
+	groups := []HunkGroup{
+		{Message: "import", Hunks: []git.Hunk{testHunk("cmd/version.go", "@@ -1 +1 @@", `+	"github.com/ademajagon/gix/internal/semver"`)}},
+		{Message: "package", Hunks: []git.Hunk{newFile}},
+	}
+
+	got := messages(OrderGroups(groups))
+	want := []string{"package", "import"}
+	if !equalStrings(got, want) {
+		t.Errorf("OrderGroups() = %v, want %v", got, want)
+	}
+}
+
+func TestOrderGroups_CycleKeepsOrder(t *testing.T) {
+	groups := []HunkGroup{
+		{Message: "a", Hunks: []git.Hunk{testHunk("a.go", "@@ -1 +1 @@", "+func Alpha() { Bravo() }")}},
+		{Message: "b", Hunks: []git.Hunk{testHunk("b.go", "@@ -1 +1 @@", "+func Bravo() { Alpha() }")}},
+	}
+
+	got := messages(OrderGroups(groups))
+	want := []string{"a", "b"}
+	if !equalStrings(got, want) {
+		t.Errorf("OrderGroups() = %v, want %v", got, want)
+	}
+}
+
+func TestMoveBefore(t *testing.T) {
+	groups := []HunkGroup{{Message: "a"}, {Message: "b"}, {Message: "c"}, {Message: "d"}}
+
+	got := messages(moveBefore(groups, 3, 1))
+	want := []string{"a", "d", "b", "c"}
+	if !equalStrings(got, want) {
+		t.Errorf("moveBefore() = %v, want %v", got, want)
+	}
+	if groups[1].Message != "b" {
+		t.Error("moveBefore modified its input")
+	}
+}
@@ -0,0 +1,279 @@
+package split
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ademajagon/gix/internal/git"
+)
+
+// Plan is a reviewed split that can be stored, exported and applied later,
+// decoupling the slow AI step from the mutation step.
+type Plan struct {
+	Base    string      `json:"base"`  // HEAD the plan was made against, empty on an unborn branch
+	Index   string      `json:"index"` // tree of the staged changes the plan was made from
+	Commits []HunkGroup `json:"commits"`
+	Dropped []git.Hunk  `json:"dropped,omitempty"`
+}
+
+// NewPlan records groups against the current HEAD and index.
+func NewPlan(groups []HunkGroup, dropped []git.Hunk) (*Plan, error) {
+	index, err := git.WriteTree()
+	if err != nil {
+		return nil, fmt.Errorf("recording index: %w", err)
+	}
+	return &Plan{
+		Base:    headCommit(),
+		Index:   index,
+		Commits: groups,
+		Dropped: dropped,
+	}, nil
+}
+
+// LoadPlan reads a plan written by Plan.Save or `gix split --export`.
+func LoadPlan(path string) (*Plan, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading plan: %w", err)
+	}
+
+	var p Plan
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("parsing plan: %w", err)
+	}
+	if len(p.Commits) == 0 {
+		return nil, fmt.Errorf("plan %s has no commits", path)
+	}
+	return &p, nil
+}
+
+// Check makes sure HEAD and the staged changes are still what the plan was
+// made from, otherwise its hunks may no longer apply.
+func (p *Plan) Check() error {
+	if head := headCommit(); head != p.Base {
+		return fmt.Errorf("HEAD is %s but the plan was made against %s", shortHash(head), shortHash(p.Base))
+	}
+	index, err := git.WriteTree()
+	if err != nil {
+		return fmt.Errorf("recording index: %w", err)
+	}
+	if index != p.Index {
+		return fmt.Errorf("staged changes differ from the ones the plan was made from")
+	}
+	return nil
+}
+
+// JSON returns the plan as indented JSON.
+func (p *Plan) JSON() ([]byte, error) {
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("marshalling plan: %w", err)
+	}
+	return append(data, '\n'), nil
+}
+
+// Save writes the plan as JSON to path.
+func (p *Plan) Save(path string) error {
+	data, err := p.JSON()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, data, 0o644); err != nil {
+		return fmt.Errorf("writing plan: %w", err)
+	}
+	return nil
+}
+
+// Markdown renders the plan for review, e.g. in a pull request.
+func (p *Plan) Markdown() string {
+	var b strings.Builder
+
+	fmt.Fprintf(&b, "# Split plan\n\n")
+	if p.Base != "" {
+		fmt.Fprintf(&b, "Base: `%s`\n\n", shortHash(p.Base))
+	}
+	fmt.Fprintf(&b, "%d commit(s)", len(p.Commits))
+	if len(p.Dropped) > 0 {
+		fmt.Fprintf(&b, ", %d hunk(s) left unstaged", len(p.Dropped))
+	}
+	b.WriteString("\n")
+
+	writeHunks := func(hunks []git.Hunk) {
+		b.WriteString("\n```diff\n")
+		for _, h := range hunks {
+			b.WriteString(h.Body)
+			b.WriteString("\n")
+		}
+		b.WriteString("```\n")
+	}
+
+	for i, c := range p.Commits {
+		subject, body := splitMessage(c.Message)
+		fmt.Fprintf(&b, "\n## %d. %s\n", i+1, subject)
+		if body != "" {
+			fmt.Fprintf(&b, "\n%s\n", body)
+		}
+		writeHunks(c.Hunks)
+	}
+
+	if len(p.Dropped) > 0 {
+		b.WriteString("\n## Left unstaged\n")
+		writeHunks(p.Dropped)
+	}
+
+	return b.String()
+}
+
+// Export writes one `git format-patch` style file per commit to dir, plus
+// plan.json for `gix split --apply-plan`. Each patch is the diff between the
+// trees the split would commit, with three lines of context even for
+// --granular plans, so they apply in order with `git am` on the plan's base.
+// From and Date are the identity and time git would record for the split
+// commits. It returns the paths written.
+func (p *Plan) Export(dir string) ([]string, error) {
+	author, date, err := patchIdent()
+	if err != nil {
+		return nil, err
+	}
+	patches, err := p.patches()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating export dir: %w", err)
+	}
+
+	var paths []string
+	for i, c := range p.Commits {
+		subject, body := splitMessage(c.Message)
+
+		var b strings.Builder
+		fmt.Fprintf(&b, "From 0000000000000000000000000000000000000000 Mon Sep 17 00:00:00 2001\n")
+		fmt.Fprintf(&b, "From: %s\n", author)
+		fmt.Fprintf(&b, "Date: %s\n", date)
+		fmt.Fprintf(&b, "Subject: [PATCH %d/%d] %s\n\n", i+1, len(p.Commits), subject)
+		if body != "" {
+			fmt.Fprintf(&b, "%s\n\n", body)
+		}
+		b.WriteString("---\n")
+		b.WriteString(patches[i])
+		b.WriteString("-- \ngix\n")
+
+		path := filepath.Join(dir, fmt.Sprintf("%04d-%s.patch", i+1, slug(subject)))
+		if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+			return nil, fmt.Errorf("writing patch: %w", err)
+		}
+		paths = append(paths, path)
+	}
+
+	planPath := filepath.Join(dir, "plan.json")
+	if err := p.Save(planPath); err != nil {
+		return nil, err
+	}
+	return append(paths, planPath), nil
+}
+
+// patches builds the tree of every commit in a private index, like
+// ApplyGroups does, and returns the diff each one adds to the previous.
+func (p *Plan) patches() ([]string, error) {
+	path, err := journalPath()
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("creating index dir: %w", err)
+	}
+	index := git.Index{Path: filepath.Join(filepath.Dir(path), "export-index")}
+	defer index.Remove()
+
+	if err := index.ReadTree(p.Base); err != nil {
+		return nil, fmt.Errorf("preparing index: %w", err)
+	}
+	prev := p.Base
+	if prev == "" {
+		if prev, err = git.EmptyTree(); err != nil {
+			return nil, err
+		}
+	}
+
+	var patches []string
+	var builder patchBuilder
+	for i, c := range p.Commits {
+		patch, zeroContext := builder.build(c.Hunks)
+		if err := index.Apply(patch, zeroContext); err != nil {
+			return nil, fmt.Errorf("commit %d: %w", i+1, err)
+		}
+		tree, err := index.WriteTree()
+		if err != nil {
+			return nil, fmt.Errorf("commit %d: %w", i+1, err)
+		}
+		diff, err := git.DiffTrees(prev, tree)
+		if err != nil {
+			return nil, fmt.Errorf("commit %d: %w", i+1, err)
+		}
+		patches = append(patches, diff)
+		prev = tree
+	}
+	return patches, nil
+}
+
+// patchIdent returns the author and date git would give a commit made now,
+// GIT_AUTHOR_NAME, GIT_AUTHOR_EMAIL and GIT_AUTHOR_DATE included.
+func patchIdent() (author, date string, err error) {
+	out, err := git.Var("GIT_AUTHOR_IDENT")
+	if err != nil {
+		return "", "", fmt.Errorf("reading author identity: %w", err)
+	}
+
+	// "Name <email> 1700000000 +0100"
+	end := strings.LastIndex(out, ">")
+	fields := strings.Fields(out[end+1:])
+	if end < 0 || len(fields) != 2 {
+		return "", "", fmt.Errorf("unexpected author identity %q", out)
+	}
+	secs, err := strconv.ParseInt(fields[0], 10, 64)
+	if err != nil {
+		return "", "", fmt.Errorf("unexpected author date %q", out)
+	}
+	zone, err := time.Parse("-0700", fields[1])
+	if err != nil {
+		return "", "", fmt.Errorf("unexpected author time zone %q", out)
+	}
+	return out[:end+1], time.Unix(secs, 0).In(zone.Location()).Format(time.RFC1123Z), nil
+}
+
+var slugPattern = regexp.MustCompile(`[^a-z0-9]+`)
+
+// slug turns a subject into a file name fragment like format-patch does.
+func slug(subject string) string {
+	s := strings.Trim(slugPattern.ReplaceAllString(strings.ToLower(subject), "-"), "-")
+	if len(s) > 52 {
+		s = strings.TrimRight(s[:52], "-")
+	}
+	if s == "" {
+		return "commit"
+	}
+	return s
+}
+
+func splitMessage(msg string) (subject, body string) {
+	subject, body, _ = strings.Cut(strings.TrimSpace(msg), "\n")
+	return strings.TrimSpace(subject), strings.TrimSpace(body)
+}
+
+func shortHash(h string) string {
+	if h == "" {
+		return "(no commits)"
+	}
+	if len(h) > 7 {
+		return h[:7]
+	}
+	return h
+}
@@ -0,0 +1,141 @@
+package split
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ademajagon/gix/internal/git"
+)
+
+func testPlan() *Plan {
+	hunks := todoHunks()
+	return &Plan{
+		Base:  "0123456789abcdef",
+		Index: "fedcba9876543210",
+		Commits: []HunkGroup{
+			{Hunks: hunks[:2], Message: "feat(split): add plan export\n\nWrites one patch per commit."},
+			{Hunks: hunks[2:], Message: "docs: describe export"},
+		},
+	}
+}
+
+func TestPlan_Markdown(t *testing.T) {
+	md := testPlan().Markdown()
+
+	for _, want := range []string{
+		"Base: `0123456`",
+		"## 1. feat(split): add plan export\n\nWrites one patch per commit.\n",
+		"## 2. docs: describe export",
+		"```diff\n@@ -1 +1 @@\n+docs\n```",
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("markdown missing %q:\n%s", want, md)
+		}
+	}
+}
+
+func TestPlan_SaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	plan := testPlan()
+
+	if err := plan.Save(path); err != nil {
+		t.Fatalf("Save() failed: %v", err)
+	}
+	loaded, err := LoadPlan(path)
+	if err != nil {
+		t.Fatalf("LoadPlan() failed: %v", err)
+	}
+
+	if loaded.Base != plan.Base || loaded.Index != plan.Index {
+		t.Errorf("base/index not preserved: %+v", loaded)
+	}
+	if len(loaded.Commits) != 2 || loaded.Commits[0].Hunks[1] != plan.Commits[0].Hunks[1] {
+		t.Errorf("commits not preserved: %+v", loaded.Commits)
+	}
+}
+
+func TestLoadPlan_NoCommits(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "plan.json")
+	os.WriteFile(path, []byte(`{"base": "abc", "commits": []}`), 0o644)
+
+	if _, err := LoadPlan(path); err == nil {
+		t.Fatal("expected error for empty plan, got nil")
+	}
+}
+
+func TestPlan_Export(t *testing.T) {
+	newRepo(t)
+	t.Setenv("GIT_AUTHOR_DATE", "1700000000 +0100")
+	stageChanges(t)
+	base := headCommit()
+
+	// zero-context hunks as --granular makes them
+	hunks, err := git.ParseHunksUnified(1<<20, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := NewPlan([]HunkGroup{
+		{Hunks: hunks[:1], Message: "feat(split): add plan export\n\nWrites one patch per commit."},
+		{Hunks: hunks[1:], Message: "docs: describe export"},
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	paths, err := plan.Export(dir)
+	if err != nil {
+		t.Fatalf("Export() failed: %v", err)
+	}
+
+	want := []string{"0001-feat-split-add-plan-export.patch", "0002-docs-describe-export.patch", "plan.json"}
+	if len(paths) != len(want) {
+		t.Fatalf("expected %d files, got %v", len(want), paths)
+	}
+	for i, name := range want {
+		if filepath.Base(paths[i]) != name {
+			t.Errorf("file %d: got %q, want %q", i, filepath.Base(paths[i]), name)
+		}
+	}
+
+	data, err := os.ReadFile(paths[0])
+	if err != nil {
+		t.Fatalf("reading patch: %v", err)
+	}
+	patch := string(data)
+	for _, want := range []string{
+		"From: Ada <ada@example.com>\nDate: Tue, 14 Nov 2023 23:13:20 +0100\n",
+		"Subject: [PATCH 1/2] feat(split): add plan export\n\nWrites one patch per commit.\n\n---\n",
+		"@@ -1,3 +1,4 @@\n+a header\n a line x\n",
+	} {
+		if !strings.Contains(patch, want) {
+			t.Errorf("patch missing %q:\n%s", want, patch)
+		}
+	}
+
+	// the patches rebuild the staged changes with git am
+	gitRun(t, "reset", "-q", "--hard", base)
+	gitRun(t, append([]string{"am", "-q"}, paths[:2]...)...)
+	if tree, _ := git.TreeOf("HEAD"); tree != plan.Index {
+		t.Error("applied patches differ from the staged changes")
+	}
+	if got := gitRun(t, "log", "--format=%an %ad %s", "--date=raw", base+"..HEAD"); got != "Ada 1700000000 +0100 docs: describe export\n"+
+		"Ada 1700000000 +0100 feat(split): add plan export" {
+		t.Errorf("applied commits:\n%s", got)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	cases := map[string]string{
+		"feat(api): Add v2 endpoints!": "feat-api-add-v2-endpoints",
+		"":                             "commit",
+		strings.Repeat("word ", 20):    "word-word-word-word-word-word-word-word-word-word-wo",
+	}
+	for in, want := range cases {
+		if got := slug(in); got != want {
+			t.Errorf("slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package split
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Progress reports how far grouping has got, for display while it runs.
+type Progress struct {
+	Embedded int // hunks embedded so far
+	Hunks    int // hunks to embed, 0 when nothing is embedded
+	Messages int // commit messages generated so far
+	Groups   int // commit messages to generate
+}
+
+// String renders the progress as e.g. "embedding 120/300 hunks, messages 3/7".
+func (p Progress) String() string {
+	var parts []string
+	if p.Hunks > 0 {
+		parts = append(parts, fmt.Sprintf("embedding %d/%d hunks", p.Embedded, p.Hunks))
+	}
+	if p.Groups > 0 {
+		parts = append(parts, fmt.Sprintf("messages %d/%d", p.Messages, p.Groups))
+	}
+	return strings.Join(parts, ", ")
+}
+
+// tracker accumulates progress from concurrent workers and reports every
+// change, one at a time.
+type tracker struct {
+	mu     sync.Mutex
+	state  Progress
+	report func(Progress)
+}
+
+func newTracker(report func(Progress)) *tracker {
+	return &tracker{report: report}
+}
+
+func (t *tracker) update(fn func(*Progress)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fn(&t.state)
+	if t.report != nil {
+		t.report(t.state)
+	}
+}
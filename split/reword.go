@@ -0,0 +1,49 @@
+package split
+
+import (
+	"fmt"
+
+	"github.com/ademajagon/gix/internal/git"
+)
+
+// RangeCommits returns the commits in base..tip, oldest first. An empty
+// base means everything up to tip.
+func RangeCommits(base, tip string) ([]string, error) {
+	return git.RevList("--reverse", revRange(base, tip))
+}
+
+// RewordCommits gives the commits in messages, all in base..HEAD, their new
+// message. The commits after base are recreated with their own tree and
+// author, a rebase that cannot conflict because no tree changes, and HEAD
+// moves once at the end. The index and working tree are not touched. Check
+// the range with CheckRewrite first.
+func RewordCommits(base string, messages map[string]string) error {
+	head := headCommit()
+	commits, err := RangeCommits(base, head)
+	if err != nil {
+		return err
+	}
+
+	parent, changed := base, false
+	for _, c := range commits {
+		msg, reworded := messages[c]
+		if !reworded && !changed {
+			parent = c // commits before the first reworded one stay as they are
+			continue
+		}
+		if !reworded {
+			if msg, err = git.Message(c); err != nil {
+				return err
+			}
+		}
+		if parent, err = git.CopyCommit(c, parent, msg); err != nil {
+			return fmt.Errorf("rewriting %s: %w", shortHash(c), err)
+		}
+		changed = true
+	}
+
+	if !changed {
+		return nil
+	}
+	return git.UpdateRef("HEAD", parent, head, "gix reword")
+}
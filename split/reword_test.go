@@ -0,0 +1,129 @@
+package split
+
+import (
+	"strings"
+	"testing"
+)
+
+// rewordHistory builds initial ← two ← three ← four, each by another
+// author, and returns their hashes oldest first.
+func rewordHistory(t *testing.T) []string {
+	t.Helper()
+	newRepo(t)
+	commits := []string{headCommit()}
+	for _, c := range []struct{ name, author, date string }{
+		{"two", "Bob <bob@example.com>", "1600000000 +0200"},
+		{"three", "Carol <carol@example.com>", "1650000000 -0500"},
+		{"four", "Dan <dan@example.com>", "1700000000 +0000"},
+	} {
+		writeFile(t, c.name+".txt", c.name+"\n")
+		commits = append(commits, commitAs(t, c.author, c.date, c.name))
+	}
+	return commits
+}
+
+// history lists tree, author, date and subject of every commit, oldest
+// first.
+func history(t *testing.T) []string {
+	t.Helper()
+	return strings.Split(gitRun(t, "log", "--reverse", "--date=raw", "--format=%T %an %ad %s"), "\n")
+}
+
+func TestRewordCommits_Middle(t *testing.T) {
+	commits := rewordHistory(t)
+	writeFile(t, "a.txt", "unstaged\n")
+	writeFile(t, "four.txt", "staged\n")
+	gitRun(t, "add", "four.txt")
+	status := gitRun(t, "status", "--porcelain")
+	before := history(t)
+
+	if err := RewordCommits(commits[1], map[string]string{commits[2]: "feat: three reworded\n\nWith a body."}); err != nil {
+		t.Fatal(err)
+	}
+
+	after := history(t)
+	if len(after) != len(before) {
+		t.Fatalf("history has %d commits, want %d", len(after), len(before))
+	}
+	for i := range before {
+		want := before[i]
+		if i == 2 {
+			want = strings.TrimSuffix(want, "three") + "feat: three reworded"
+		}
+		if after[i] != want {
+			t.Errorf("commit %d = %q, want %q", i, after[i], want)
+		}
+	}
+	if got := gitRun(t, "rev-parse", "HEAD~2"); got != commits[1] {
+		t.Error("commits before the reworded one were recreated")
+	}
+	if got := gitRun(t, "log", "-1", "--format=%B", "HEAD~1"); got != "feat: three reworded\n\nWith a body." {
+		t.Errorf("message = %q", got)
+	}
+	if got := gitRun(t, "status", "--porcelain"); got != status {
+		t.Errorf("index or work tree changed:\n%s", got)
+	}
+	if got := gitRun(t, "reflog", "-1", "--format=%gs", "HEAD"); got != "gix reword" {
+		t.Errorf("reflog = %q", got)
+	}
+}
+
+func TestRewordCommits_DirtyWorkTree(t *testing.T) {
+	commits := rewordHistory(t)
+	writeFile(t, "two.txt", "unstaged\n")
+	writeFile(t, "new.txt", "untracked\n")
+	status := gitRun(t, "status", "--porcelain")
+
+	// rewording an older commit replays nothing through the work tree
+	base, tip, err := ResolveRange(commits[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckRewrite(base, tip, nil, false); err != nil {
+		t.Fatalf("expected a dirty work tree to be accepted, got %v", err)
+	}
+	if err := RewordCommits(base, map[string]string{tip: "feat: two"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if got := gitRun(t, "log", "-1", "--format=%s", "HEAD~2"); got != "feat: two" {
+		t.Errorf("message = %q", got)
+	}
+	if got := gitRun(t, "status", "--porcelain"); got != status {
+		t.Errorf("work tree changed:\n%s", got)
+	}
+	if got := gitRun(t, "show", "HEAD:two.txt"); got != "two" {
+		t.Errorf("the unstaged change was committed: %q", got)
+	}
+}
+
+func TestRewordCommits_Root(t *testing.T) {
+	commits := rewordHistory(t)
+	before := history(t)
+
+	messages := map[string]string{commits[0]: "chore: initial import", commits[3]: "feat: four"}
+	if err := RewordCommits("", messages); err != nil {
+		t.Fatal(err)
+	}
+
+	after := history(t)
+	want := append([]string(nil), before...)
+	want[0] = strings.TrimSuffix(want[0], "initial") + "chore: initial import"
+	want[3] = strings.TrimSuffix(want[3], "four") + "feat: four"
+	if strings.Join(after, "\n") != strings.Join(want, "\n") {
+		t.Errorf("history:\n%s\nwant:\n%s", strings.Join(after, "\n"), strings.Join(want, "\n"))
+	}
+	if parents := gitRun(t, "rev-list", "--max-parents=0", "HEAD"); parents == commits[0] || strings.Contains(parents, "\n") {
+		t.Errorf("expected one new root commit, got %q", parents)
+	}
+}
+
+func TestRewordCommits_NothingChanged(t *testing.T) {
+	commits := rewordHistory(t)
+	if err := RewordCommits(commits[0], nil); err != nil {
+		t.Fatal(err)
+	}
+	if headCommit() != commits[3] {
+		t.Error("HEAD moved without reworded commits")
+	}
+}
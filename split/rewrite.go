@@ -0,0 +1,204 @@
+package split
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/ademajagon/gix/internal/git"
+)
+
+// ResolveRange turns a `gix split` argument into the commits to rewrite:
+// "<commit>" splits a single commit, "<base>..<tip>" everything after base
+// up to tip. base is "" when the range starts at the root commit.
+func ResolveRange(arg string) (base, tip string, err error) {
+	if from, to, isRange := strings.Cut(arg, ".."); isRange {
+		if from == "" {
+			return "", "", fmt.Errorf("range %q needs a start commit", arg)
+		}
+		if to == "" {
+			to = "HEAD"
+		}
+		if base, err = git.ResolveRef(from); err != nil {
+			return "", "", fmt.Errorf("unknown commit %q", from)
+		}
+		if tip, err = git.ResolveRef(to); err != nil {
+			return "", "", fmt.Errorf("unknown commit %q", to)
+		}
+		return base, tip, nil
+	}
+
+	if tip, err = git.ResolveRef(arg); err != nil {
+		return "", "", fmt.Errorf("unknown commit %q", arg)
+	}
+	parents, err := git.Parents(tip)
+	if err != nil {
+		return "", "", err
+	}
+	switch len(parents) {
+	case 0:
+		return "", tip, nil
+	case 1:
+		return parents[0], tip, nil
+	default:
+		return "", "", fmt.Errorf("%s is a merge commit, merges cannot be split", shortHash(tip))
+	}
+}
+
+// CheckRewrite refuses to split base..tip when rewriting it would not be
+// safe: the commits must be on the current branch with no merges up to
+// HEAD, and no other operation may be in progress. Commits that were
+// pushed, or live on a protected branch, are only rewritten with force.
+// The working tree is not looked at, see CheckReplay.
+func CheckRewrite(base, tip string, protected []string, force bool) error {
+	if op := operationInProgress(); op != "" {
+		return fmt.Errorf("a %s is in progress, finish or abort it first", op)
+	}
+	if j, err := loadJournal(); err == nil && !j.Finished {
+		return fmt.Errorf("a split is already in progress, run `gix split --continue` or `gix split --abort`")
+	}
+
+	head := headCommit()
+	if head == "" || !git.IsAncestor(tip, head) {
+		return fmt.Errorf("%s is not on the current branch", shortHash(tip))
+	}
+	if base != "" && (base == tip || !git.IsAncestor(base, tip)) {
+		return fmt.Errorf("no commits between %s and %s", shortHash(base), shortHash(tip))
+	}
+
+	commits, err := git.RevList(revRange(base, tip))
+	if err != nil {
+		return err
+	}
+	merges, err := git.RevList("--merges", revRange(base, head))
+	if err != nil {
+		return err
+	}
+	if len(merges) > 0 {
+		return fmt.Errorf("the history to rewrite contains merge commits, which cannot be replayed")
+	}
+
+	if force {
+		return nil
+	}
+	if branch := git.CurrentBranch(); slices.Contains(protected, branch) {
+		return fmt.Errorf("%s is a protected branch, use --force to rewrite it anyway", branch)
+	}
+	// commits come newest first, if the oldest was not pushed none was
+	remotes, err := git.RemoteBranchesContaining(commits[len(commits)-1])
+	if err != nil {
+		return err
+	}
+	if len(remotes) > 0 {
+		return fmt.Errorf("%s was already pushed to %s, use --force to rewrite it anyway",
+			shortHash(commits[len(commits)-1]), strings.Join(remotes, ", "))
+	}
+	return nil
+}
+
+// CheckReplay refuses to rewrite up to tip when the commits after it must
+// be replayed with `git rebase` and the working tree is not clean. Only
+// RewriteCommits replays, RewordCommits never touches the working tree.
+func CheckReplay(tip string) error {
+	if tip == headCommit() {
+		return nil
+	}
+	clean, err := git.IsClean()
+	if err != nil {
+		return err
+	}
+	if !clean {
+		return fmt.Errorf("the commits after %s must be replayed, commit or stash your changes first", shortHash(tip))
+	}
+	return nil
+}
+
+// RewriteCommits replaces the commits in base..tip with one commit per
+// group, built with plumbing like ApplyGroups. Together the groups must
+// reproduce tip's tree exactly, so nothing is lost from history. When tip
+// is HEAD the branch moves in one step, otherwise the commits after tip are
+// replayed with `git rebase --onto`; if that stops, the rebase is finished
+// or aborted the usual way. Each new commit keeps the author and date of
+// the oldest replaced commit that touched its files. Check the range with
+// CheckRewrite first.
+func RewriteCommits(base, tip string, groups []HunkGroup) error {
+	authors := make([]string, len(groups))
+	for i, g := range groups {
+		author, err := sourceCommit(base, tip, g.Hunks)
+		if err != nil {
+			return err
+		}
+		authors[i] = author
+	}
+	return start(&journal{OrigHead: headCommit(), Base: base, Tip: tip, Groups: groups, Authors: authors})
+}
+
+// sourceCommit returns the oldest commit in base..tip that changed a file
+// of hunks, tip if there is none.
+func sourceCommit(base, tip string, hunks []git.Hunk) (string, error) {
+	var files []string
+	for _, h := range hunks {
+		if !slices.Contains(files, h.FilePath) {
+			files = append(files, h.FilePath)
+		}
+	}
+
+	commits, err := git.RevList(append([]string{revRange(base, tip), "--"}, files...)...)
+	if err != nil {
+		return "", err
+	}
+	if len(commits) == 0 {
+		return tip, nil
+	}
+	// newest first
+	return commits[len(commits)-1], nil
+}
+
+// checkRewrite makes sure the built commits end with the tree of the
+// commit being split.
+func (j *journal) checkRewrite() error {
+	want, err := git.TreeOf(j.Tip)
+	if err != nil {
+		return err
+	}
+	got, err := git.TreeOf(j.head())
+	if err != nil {
+		return err
+	}
+	if got != want {
+		return fmt.Errorf("the split commits do not add up to %s (dropped hunks or changes without a text diff), refusing to rewrite history",
+			shortHash(j.Tip))
+	}
+	return nil
+}
+
+// operationInProgress names the git operation, such as a rebase, that
+// currently owns the working tree, or returns "".
+func operationInProgress() string {
+	dir, err := git.Dir()
+	if err != nil {
+		return ""
+	}
+	for _, op := range []struct{ file, name string }{
+		{"rebase-merge", "rebase"},
+		{"rebase-apply", "rebase"},
+		{"MERGE_HEAD", "merge"},
+		{"CHERRY_PICK_HEAD", "cherry-pick"},
+		{"REVERT_HEAD", "revert"},
+		{"BISECT_LOG", "bisect"},
+	} {
+		if _, err := os.Stat(filepath.Join(dir, op.file)); err == nil {
+			return op.name
+		}
+	}
+	return ""
+}
+
+func revRange(base, tip string) string {
+	if base == "" {
+		return tip
+	}
+	return base + ".." + tip
+}
@@ -0,0 +1,195 @@
+package split
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ademajagon/gix/internal/git"
+)
+
+// commitAs commits every change as author at date and returns the commit.
+func commitAs(t *testing.T, author, date, message string) string {
+	t.Helper()
+	gitRun(t, "add", "-A")
+	gitRun(t, "commit", "-q", "--author", author, "--date", date, "-m", message)
+	return headCommit()
+}
+
+func TestResolveRange(t *testing.T) {
+	newRepo(t)
+	root := headCommit()
+	writeFile(t, "a.txt", "changed\n")
+	second := commitAs(t, "Bob <bob@example.com>", "1600000000 +0200", "second")
+
+	tests := []struct {
+		arg        string
+		base, tip  string
+		wantErrSub string
+	}{
+		{arg: "HEAD", base: root, tip: second},
+		{arg: root, base: "", tip: root},
+		{arg: "HEAD~1..", base: root, tip: second},
+		{arg: root + "..HEAD", base: root, tip: second},
+		{arg: "..HEAD", wantErrSub: "needs a start commit"},
+		{arg: "nope", wantErrSub: "unknown commit"},
+	}
+	for _, tt := range tests {
+		base, tip, err := ResolveRange(tt.arg)
+		if tt.wantErrSub != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErrSub) {
+				t.Errorf("ResolveRange(%q): expected error containing %q, got %v", tt.arg, tt.wantErrSub, err)
+			}
+			continue
+		}
+		if err != nil || base != tt.base || tip != tt.tip {
+			t.Errorf("ResolveRange(%q) = %q, %q, %v", tt.arg, base, tip, err)
+		}
+	}
+
+	gitRun(t, "checkout", "-q", "-b", "side", root)
+	writeFile(t, "b.txt", "side\n")
+	commitAs(t, "Bob <bob@example.com>", "1600000000 +0200", "side")
+	gitRun(t, "checkout", "-q", "main")
+	gitRun(t, "merge", "-q", "--no-edit", "side")
+	if _, _, err := ResolveRange("HEAD"); err == nil || !strings.Contains(err.Error(), "merge") {
+		t.Errorf("expected merge commits to be refused, got %v", err)
+	}
+}
+
+func TestCheckRewrite(t *testing.T) {
+	newRepo(t)
+	root := headCommit()
+	writeFile(t, "a.txt", "changed\n")
+	second := commitAs(t, "Bob <bob@example.com>", "1600000000 +0200", "second")
+
+	if err := CheckRewrite(root, second, nil, false); err != nil {
+		t.Fatalf("expected the unpushed commit to be accepted, got %v", err)
+	}
+
+	err := CheckRewrite(root, second, []string{"main"}, false)
+	if err == nil || !strings.Contains(err.Error(), "protected") {
+		t.Errorf("expected protected branch to be refused, got %v", err)
+	}
+	if err := CheckRewrite(root, second, []string{"main"}, true); err != nil {
+		t.Errorf("expected --force to allow a protected branch, got %v", err)
+	}
+
+	remote := t.TempDir()
+	gitRun(t, "init", "-q", "--bare", remote)
+	gitRun(t, "remote", "add", "origin", remote)
+	gitRun(t, "push", "-q", "origin", "main")
+	if err := CheckRewrite(root, second, nil, false); err == nil || !strings.Contains(err.Error(), "pushed to origin/main") {
+		t.Errorf("expected pushed commits to be refused, got %v", err)
+	}
+	if err := CheckRewrite(root, second, nil, true); err != nil {
+		t.Errorf("expected --force to allow pushed commits, got %v", err)
+	}
+
+	writeFile(t, "b.txt", "third\n")
+	commitAs(t, "Bob <bob@example.com>", "1600000000 +0200", "third")
+
+	gitRun(t, "checkout", "-q", "-b", "side", root)
+	writeFile(t, "side.txt", "side\n")
+	commitAs(t, "Bob <bob@example.com>", "1600000000 +0200", "side")
+	gitRun(t, "checkout", "-q", "main")
+	gitRun(t, "merge", "-q", "--no-edit", "side")
+	if err := CheckRewrite(root, second, nil, true); err == nil || !strings.Contains(err.Error(), "merge") {
+		t.Errorf("expected merges after tip to be refused, got %v", err)
+	}
+
+	gitRun(t, "checkout", "-q", "side")
+	if err := CheckRewrite(root, second, nil, true); err == nil || !strings.Contains(err.Error(), "not on the current branch") {
+		t.Errorf("expected a commit of another branch to be refused, got %v", err)
+	}
+}
+
+func TestCheckReplay(t *testing.T) {
+	newRepo(t)
+	writeFile(t, "a.txt", "changed\n")
+	second := commitAs(t, "Bob <bob@example.com>", "1600000000 +0200", "second")
+	writeFile(t, "b.txt", "third\n")
+	third := commitAs(t, "Bob <bob@example.com>", "1600000000 +0200", "third")
+	writeFile(t, "b.txt", "dirty\n")
+
+	// nothing comes after HEAD, so nothing is replayed
+	if err := CheckReplay(third); err != nil {
+		t.Errorf("expected splitting HEAD on a dirty work tree to be accepted, got %v", err)
+	}
+	// the commits after tip are replayed, which needs a clean work tree
+	if err := CheckReplay(second); err == nil || !strings.Contains(err.Error(), "stash") {
+		t.Errorf("expected a dirty work tree to be refused, got %v", err)
+	}
+	gitRun(t, "checkout", "-q", "b.txt")
+	if err := CheckReplay(second); err != nil {
+		t.Errorf("expected a clean work tree to be accepted, got %v", err)
+	}
+}
+
+func TestRewriteCommits(t *testing.T) {
+	newRepo(t)
+	root := headCommit()
+
+	writeFile(t, "a.txt", "a header\n"+numbered("a", 20))
+	commitAs(t, "Bob <bob@example.com>", "1600000000 +0200", "add a header")
+	writeFile(t, "b.txt", numbered("b", 20)+"b footer\n")
+	tip := commitAs(t, "Carol <carol@example.com>", "1700000000 -0500", "add b footer")
+	writeFile(t, "later.txt", "later\n")
+	commitAs(t, "Dan <dan@example.com>", "1750000000 +0000", "later")
+	final, _ := git.TreeOf("HEAD")
+
+	hunks, err := git.ParseRevisionHunks(root, tip, 1<<20, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hunks) != 2 || hunks[0].FilePath != "a.txt" {
+		t.Fatalf("unexpected hunks: %+v", hunks)
+	}
+	// regroup in the opposite order
+	groups := []HunkGroup{
+		{Hunks: hunks[1:], Message: "feat: add b footer"},
+		{Hunks: hunks[:1], Message: "feat: add a header"},
+	}
+	if err := CheckRewrite(root, tip, nil, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckReplay(tip); err != nil {
+		t.Fatal(err)
+	}
+	if err := RewriteCommits(root, tip, groups); err != nil {
+		t.Fatal(err)
+	}
+
+	if tree, _ := git.TreeOf("HEAD"); tree != final {
+		t.Error("the branch does not end with the same tree")
+	}
+	want := "Dan <dan@example.com> 1750000000 +0000 later\n" +
+		"Bob <bob@example.com> 1600000000 +0200 feat: add a header\n" +
+		"Carol <carol@example.com> 1700000000 -0500 feat: add b footer"
+	if got := gitRun(t, "log", "--format=%an <%ae> %ad %s", "--date=raw", root+"..HEAD"); got != want {
+		t.Errorf("history:\n%s\nwant:\n%s", got, want)
+	}
+	if j, err := loadJournal(); err != nil || !j.Finished || j.NewHead != headCommit() {
+		t.Errorf("journal not finished: %v", err)
+	}
+}
+
+func TestRewriteCommits_RefusesLosingChanges(t *testing.T) {
+	newRepo(t)
+	root := headCommit()
+	writeFile(t, "a.txt", "a header\n"+numbered("a", 20))
+	writeFile(t, "b.txt", numbered("b", 20)+"b footer\n")
+	tip := commitAs(t, "Bob <bob@example.com>", "1600000000 +0200", "both")
+
+	hunks, err := git.ParseRevisionHunks(root, tip, 1<<20, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the b.txt hunk is dropped
+	err = RewriteCommits(root, tip, []HunkGroup{{Hunks: hunks[:1], Message: "feat: a"}})
+	if err == nil || !strings.Contains(err.Error(), "do not add up") {
+		t.Fatalf("expected the split to be refused, got %v", err)
+	}
+	if headCommit() != tip {
+		t.Error("HEAD moved")
+	}
+}
@@ -0,0 +1,165 @@
+package split
+
+import (
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/ademajagon/gix/internal/git"
+)
+
+// Weights of the structural signals between two hunks. Signals are combined
+// as independent evidence, 1 - Π(1 - w), and only ever pull the embedding
+// similarity towards 1, see combineSimilarity.
+const (
+	weightSameFile    = 0.35
+	weightSamePackage = 0.15
+	weightImport      = 0.5
+	weightSymbolRef   = 0.6
+)
+
+// minSymbolLen ignores short identifiers such as "i", "err" or "ok" which
+// would link almost every pair of hunks.
+const minSymbolLen = 4
+
+var (
+	definitionPatterns = []*regexp.Regexp{
+		regexp.MustCompile(`^\s*func\s+(?:\([^)]*\)\s*)?([A-Za-z_]\w*)`),      // Go funcs and methods
+		regexp.MustCompile(`^\s*(?:type|var|const)\s+([A-Za-z_]\w*)`),         // Go declarations
+		regexp.MustCompile(`^\s*(?:def|class|function|fn|interface)\s+(\w+)`), // Python, JS, Rust, TS
+	}
+	identPattern   = regexp.MustCompile(`[A-Za-z_]\w*`)
+	quotedPattern  = regexp.MustCompile(`"([^"\s]+)"`)
+	requirePattern = regexp.MustCompile(`^\s*(?:require\s+)?([\w.-]+\.[\w.-]+/\S+)\s+v\d`)
+)
+
+// hunkFacts holds what the structural signals need to know about a hunk.
+type hunkFacts struct {
+	file    string
+	dir     string
+	defines map[string]bool // symbols declared on changed lines
+	idents  map[string]bool // identifiers used on changed lines
+	imports []string        // quoted paths on changed lines
+
+	// The same facts restricted to added lines, used for ordering: a
+	// commit that introduces a symbol must come before one that uses it.
+	added struct {
+		defines map[string]bool
+		idents  map[string]bool
+		imports []string
+		modules []string // module paths required in go.mod
+	}
+	newFile bool
+}
+
+func extractFacts(h git.Hunk) hunkFacts {
+	f := hunkFacts{
+		file:    h.FilePath,
+		dir:     path.Dir(h.FilePath),
+		defines: make(map[string]bool),
+		idents:  make(map[string]bool),
+		newFile: strings.Contains(h.FileHeader, "\nnew file mode"),
+	}
+	f.added.defines = make(map[string]bool)
+	f.added.idents = make(map[string]bool)
+	isGoMod := path.Base(h.FilePath) == "go.mod"
+
+	for _, line := range h.Lines() {
+		if !strings.HasPrefix(line, "+") && !strings.HasPrefix(line, "-") {
+			continue
+		}
+		code := line[1:]
+		added := line[0] == '+'
+
+		for _, re := range definitionPatterns {
+			if m := re.FindStringSubmatch(code); m != nil && len(m[1]) >= minSymbolLen {
+				f.defines[m[1]] = true
+				if added {
+					f.added.defines[m[1]] = true
+				}
+			}
+		}
+		for _, id := range identPattern.FindAllString(code, -1) {
+			if len(id) >= minSymbolLen {
+				f.idents[id] = true
+				if added {
+					f.added.idents[id] = true
+				}
+			}
+		}
+		for _, m := range quotedPattern.FindAllStringSubmatch(code, -1) {
+			if strings.Contains(m[1], "/") {
+				f.imports = append(f.imports, m[1])
+				if added {
+					f.added.imports = append(f.added.imports, m[1])
+				}
+			}
+		}
+		if isGoMod && added {
+			if m := requirePattern.FindStringSubmatch(code); m != nil {
+				f.added.modules = append(f.added.modules, m[1])
+			}
+		}
+	}
+
+	return f
+}
+
+// structuralSimilarity scores how strongly two hunks are related by code
+// structure rather than by wording: same file, same package, one importing
+// the other's package, or one referencing a symbol the other declares.
+func structuralSimilarity(a, b hunkFacts) float64 {
+	var weights []float64
+
+	switch {
+	case a.file == b.file:
+		weights = append(weights, weightSameFile)
+	case a.dir == b.dir:
+		weights = append(weights, weightSamePackage)
+	}
+
+	if a.dir != b.dir && (importsDir(a.imports, b.dir) || importsDir(b.imports, a.dir)) {
+		weights = append(weights, weightImport)
+	}
+
+	if referencesAny(a.idents, b.defines) || referencesAny(b.idents, a.defines) {
+		weights = append(weights, weightSymbolRef)
+	}
+
+	miss := 1.0
+	for _, w := range weights {
+		miss *= 1 - w
+	}
+	return 1 - miss
+}
+
+func importsDir(imports []string, dir string) bool {
+	if dir == "." || dir == "" {
+		return false
+	}
+	for _, imp := range imports {
+		if imp == dir || strings.HasSuffix(imp, "/"+dir) {
+			return true
+		}
+	}
+	return false
+}
+
+func referencesAny(idents, defines map[string]bool) bool {
+	for sym := range defines {
+		if idents[sym] {
+			return true
+		}
+	}
+	return false
+}
+
+// combineSimilarity raises the embedding similarity by the structural score,
+// closing that fraction of the remaining gap to 1. Unrelated structure never
+// lowers the embedding similarity.
+func combineSimilarity(embedding, structural float64) float64 {
+	if embedding < 0 {
+		embedding = 0
+	}
+	return embedding + (1-embedding)*structural
+}
@@ -0,0 +1,156 @@
+package split
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/ademajagon/gix/internal/git"
+)
+
+const todoHelp = `
+# Edit the plan, then save and close the editor. Commits are created from
+# top to bottom.
+#
+# Commands:
+# p, pick <message>   commit the hunks listed below with this message
+# r, regen [message]  commit the hunks listed below with a new AI message
+# s, squash           merge the hunks listed below into the previous commit
+# d, drop             leave the hunks listed below unstaged
+#
+# Hunk lines start with the hunk number, the rest of the line is ignored.
+# Move hunk lines between commits, reorder commits by moving whole blocks,
+# add a pick line to split a commit. An empty message is regenerated and
+# "\n" in a message starts a new line. Hunks that are not listed are left
+# unstaged. A plan without any commit is ignored.
+`
+
+// maxPreviewLen caps the changed-line preview shown next to each hunk.
+const maxPreviewLen = 60
+
+// FormatTodo renders groups as a `git rebase -i` style todo list, with
+// dropped hunks in a trailing drop block. Hunks are numbered by their
+// position in hunks, which ParseTodo needs to read the list back.
+func FormatTodo(groups []HunkGroup, dropped, hunks []git.Hunk) string {
+	ids := hunkIDs(hunks)
+
+	var b strings.Builder
+	writeHunks := func(hs []git.Hunk) {
+		for _, h := range hs {
+			fmt.Fprintf(&b, "\t%d %s %s  %s\n", ids[h], h.FilePath, h.Header, hunkPreview(h))
+		}
+	}
+
+	for i, g := range groups {
+		if i > 0 {
+			b.WriteString("\n")
+		}
+		fmt.Fprintf(&b, "pick %s\n", strings.ReplaceAll(g.Message, "\n", `\n`))
+		writeHunks(g.Hunks)
+	}
+	if len(dropped) > 0 {
+		b.WriteString("\ndrop\n")
+		writeHunks(dropped)
+	}
+
+	b.WriteString(todoHelp)
+	return b.String()
+}
+
+// ParseTodo reads an edited todo list back into groups. Groups whose
+// message should be (re)generated have an empty Message. Hunks that are
+// dropped or not listed at all are returned separately.
+func ParseTodo(todo string, hunks []git.Hunk) (groups []HunkGroup, dropped []git.Hunk, err error) {
+	used := make([]bool, len(hunks))
+	current := -1 // index into groups, -1 before the first command
+	dropping := false
+
+	for n, raw := range strings.Split(todo, "\n") {
+		line := strings.TrimSpace(raw)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+
+		if id, convErr := strconv.Atoi(fields[0]); convErr == nil {
+			if id < 1 || id > len(hunks) {
+				return nil, nil, fmt.Errorf("line %d: unknown hunk %d", n+1, id)
+			}
+			if used[id-1] {
+				return nil, nil, fmt.Errorf("line %d: hunk %d is listed more than once", n+1, id)
+			}
+			used[id-1] = true
+
+			switch {
+			case dropping:
+				dropped = append(dropped, hunks[id-1])
+			case current < 0:
+				return nil, nil, fmt.Errorf("line %d: hunk %d is not below a command", n+1, id)
+			default:
+				groups[current].Hunks = append(groups[current].Hunks, hunks[id-1])
+			}
+			continue
+		}
+
+		cmd, rest, _ := strings.Cut(line, " ")
+		message := strings.ReplaceAll(strings.TrimSpace(rest), `\n`, "\n")
+
+		switch cmd {
+		case "p", "pick":
+			groups = append(groups, HunkGroup{Message: message})
+			current, dropping = len(groups)-1, false
+		case "r", "regen":
+			groups = append(groups, HunkGroup{})
+			current, dropping = len(groups)-1, false
+		case "s", "squash":
+			if current < 0 {
+				return nil, nil, fmt.Errorf("line %d: squash without a previous commit", n+1)
+			}
+			dropping = false
+		case "d", "drop":
+			dropping = true
+		default:
+			return nil, nil, fmt.Errorf("line %d: unknown command %q", n+1, cmd)
+		}
+	}
+
+	// skip commits that ended up without hunks, like an emptied pick block
+	kept := groups[:0]
+	for _, g := range groups {
+		if len(g.Hunks) > 0 {
+			kept = append(kept, g)
+		}
+	}
+	groups = kept
+
+	for i, h := range hunks {
+		if !used[i] {
+			dropped = append(dropped, h)
+		}
+	}
+
+	return groups, dropped, nil
+}
+
+func hunkIDs(hunks []git.Hunk) map[git.Hunk]int {
+	ids := make(map[git.Hunk]int, len(hunks))
+	for i, h := range hunks {
+		ids[h] = i + 1
+	}
+	return ids
+}
+
+// hunkPreview returns the first changed line of a hunk, shortened.
+func hunkPreview(h git.Hunk) string {
+	for _, l := range h.Lines() {
+		if !strings.HasPrefix(l, "+") && !strings.HasPrefix(l, "-") {
+			continue
+		}
+		r := []rune(strings.Join(strings.Fields(l), " "))
+		if len(r) > maxPreviewLen {
+			return string(r[:maxPreviewLen]) + "…"
+		}
+		return string(r)
+	}
+	return ""
+}
@@ -0,0 +1,109 @@
+package split
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ademajagon/gix/internal/git"
+)
+
+func todoHunks() []git.Hunk {
+	return []git.Hunk{
+		testHunk("a.go", "@@ -1 +1 @@", "-old", "+new"),
+		testHunk("b.go", "@@ -1 +1 @@", "+b"),
+		testHunk("README.md", "@@ -1 +1 @@", "+docs"),
+	}
+}
+
+func TestFormatTodo_RoundTrip(t *testing.T) {
+	hunks := todoHunks()
+	groups := []HunkGroup{
+		{Hunks: []git.Hunk{hunks[0], hunks[1]}, Message: "feat: add thing\n\nwith a body"},
+		{Hunks: []git.Hunk{hunks[2]}, Message: "docs: readme"},
+	}
+
+	todo := FormatTodo(groups, nil, hunks)
+	if !strings.Contains(todo, "\t1 a.go @@ -1 +1 @@  -old\n") {
+		t.Errorf("expected hunk line with preview, got:\n%s", todo)
+	}
+
+	parsed, dropped, err := ParseTodo(todo, hunks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dropped) != 0 {
+		t.Errorf("expected no dropped hunks, got %d", len(dropped))
+	}
+	if len(parsed) != 2 || len(parsed[0].Hunks) != 2 || len(parsed[1].Hunks) != 1 {
+		t.Fatalf("unexpected groups: %+v", parsed)
+	}
+	if parsed[0].Message != groups[0].Message {
+		t.Errorf("multi-line message not preserved: %q", parsed[0].Message)
+	}
+}
+
+func TestParseTodo_Edits(t *testing.T) {
+	hunks := todoHunks()
+	todo := `
+regen
+	2 b.go
+pick docs: readme
+	3
+squash
+	1 a.go
+`
+	groups, dropped, err := ParseTodo(todo, hunks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(dropped) != 0 {
+		t.Errorf("expected no dropped hunks, got %d", len(dropped))
+	}
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].Message != "" || groups[0].Hunks[0].FilePath != "b.go" {
+		t.Errorf("expected regen group with b.go, got %+v", groups[0])
+	}
+	if groups[1].Message != "docs: readme" || len(groups[1].Hunks) != 2 {
+		t.Errorf("expected squashed group with 2 hunks, got %+v", groups[1])
+	}
+}
+
+func TestParseTodo_DropAndUnlisted(t *testing.T) {
+	hunks := todoHunks()
+	todo := "pick feat: a\n\t1\npick chore: empty\ndrop\n\t3\n"
+
+	groups, dropped, err := ParseTodo(todo, hunks)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(groups) != 1 {
+		t.Errorf("expected empty pick to be skipped, got %d groups", len(groups))
+	}
+	if len(dropped) != 2 || dropped[0].FilePath != "README.md" || dropped[1].FilePath != "b.go" {
+		t.Errorf("expected README.md and b.go dropped, got %+v", dropped)
+	}
+
+	again := FormatTodo(groups, dropped, hunks)
+	if !strings.Contains(again, "\ndrop\n\t3 README.md") {
+		t.Errorf("expected drop block in todo, got:\n%s", again)
+	}
+}
+
+func TestParseTodo_Errors(t *testing.T) {
+	cases := map[string]string{
+		"unknown hunk":    "pick x\n\t9\n",
+		"duplicate hunk":  "pick x\n\t1\n\t1\n",
+		"hunk first":      "\t1\npick x\n",
+		"unknown command": "fixup x\n\t1\n",
+		"leading squash":  "squash\n\t1\n",
+	}
+	for name, todo := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, _, err := ParseTodo(todo, todoHunks()); err == nil {
+				t.Error("expected error, got nil")
+			}
+		})
+	}
+}
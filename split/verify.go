@@ -0,0 +1,157 @@
+package split
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+)
+
+// VerifyPlan checks that every intermediate commit of the plan passes
+// command (e.g. "go build ./..."), keeping history bisectable. Each prefix
+// of the plan is applied on top of base (usually HEAD) in a temporary
+// worktree, so the user's working tree is never touched.
+//
+// When a commit fails, VerifyPlan tries to move a later group in front of
+// it, which fixes plans where a dependency was missed by OrderGroups. The
+// possibly reordered plan is returned, or an error with the command output
+// when no order works.
+func VerifyPlan(base string, groups []HunkGroup, command string) ([]HunkGroup, error) {
+	dir, err := os.MkdirTemp("", "gix-verify-")
+	if err != nil {
+		return nil, fmt.Errorf("creating verify dir: %w", err)
+	}
+	defer os.RemoveAll(dir)
+
+	add := exec.Command("git", "worktree", "add", "--quiet", "--detach", dir, base)
+	add.Stderr = os.Stderr
+	if err := add.Run(); err != nil {
+		return nil, fmt.Errorf("git worktree add: %w", err)
+	}
+	defer exec.Command("git", "worktree", "remove", "--force", dir).Run()
+
+	v := verifier{dir: dir, command: command}
+	order := append([]HunkGroup(nil), groups...)
+
+	b, err := v.reset(nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := 0; i < len(order); i++ {
+		fmt.Fprintf(os.Stderr, "verifying [%d/%d] %s\n", i+1, len(order), order[i].Message)
+
+		passed, output, err := v.step(b, order[i])
+		if err != nil {
+			return nil, fmt.Errorf("group %d: %w", i+1, err)
+		}
+		if passed {
+			continue
+		}
+
+		moved := false
+		for j := i + 1; j < len(order) && !moved; j++ {
+			candidate := moveBefore(order, j, i)
+			if b, err = v.reset(candidate[:i]); err != nil {
+				return nil, err
+			}
+			if ok, _, err := v.step(b, candidate[i]); err != nil || !ok {
+				continue
+			}
+			if ok, _, err := v.step(b, candidate[i+1]); err != nil || !ok {
+				continue
+			}
+			fmt.Fprintf(os.Stderr, "moved %q before %q\n", candidate[i].Message, candidate[i+1].Message)
+			order = candidate
+			i++
+			moved = true
+		}
+
+		if !moved {
+			return nil, fmt.Errorf("commit %d (%s) fails %q and no reordering fixes it:\n%s",
+				i+1, order[i].Message, command, output)
+		}
+	}
+
+	return order, nil
+}
+
+type verifier struct {
+	dir     string
+	command string
+}
+
+// reset restores the worktree to the base commit and applies prefix without running
+// the command, those groups are already known to pass.
+func (v verifier) reset(prefix []HunkGroup) (*patchBuilder, error) {
+	if err := exec.Command("git", "-C", v.dir, "reset", "--quiet", "--hard", "HEAD").Run(); err != nil {
+		return nil, fmt.Errorf("resetting verify worktree: %w", err)
+	}
+	if err := exec.Command("git", "-C", v.dir, "clean", "-fdq").Run(); err != nil {
+		return nil, fmt.Errorf("cleaning verify worktree: %w", err)
+	}
+
+	b := &patchBuilder{}
+	for i, g := range prefix {
+		if err := v.apply(b, g); err != nil {
+			return nil, fmt.Errorf("group %d: %w", i+1, err)
+		}
+	}
+	return b, nil
+}
+
+// step applies the group and runs the command, returning its output when
+// it fails.
+func (v verifier) step(b *patchBuilder, g HunkGroup) (bool, string, error) {
+	if err := v.apply(b, g); err != nil {
+		return false, "", err
+	}
+
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", v.command)
+	} else {
+		cmd = exec.Command("sh", "-c", v.command)
+	}
+	cmd.Dir = v.dir
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	cmd.Stderr = &out
+	if err := cmd.Run(); err != nil {
+		if _, ok := err.(*exec.ExitError); ok {
+			return false, out.String(), nil
+		}
+		return false, "", fmt.Errorf("running %q: %w", v.command, err)
+	}
+	return true, "", nil
+}
+
+func (v verifier) apply(b *patchBuilder, g HunkGroup) error {
+	patch, zeroContext := b.build(g.Hunks)
+
+	args := []string{"-C", v.dir, "apply"}
+	if zeroContext {
+		args = append(args, "--unidiff-zero")
+	}
+	cmd := exec.Command("git", append(args, "-")...)
+	cmd.Stdin = bytes.NewBufferString(patch)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("git apply: %w: %s", err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
+
+// moveBefore returns a copy of groups with the group at from moved to index
+// to (to < from).
+func moveBefore(groups []HunkGroup, from, to int) []HunkGroup {
+	out := make([]HunkGroup, 0, len(groups))
+	out = append(out, groups[:to]...)
+	out = append(out, groups[from])
+	out = append(out, groups[to:from]...)
+	return append(out, groups[from+1:]...)
+}
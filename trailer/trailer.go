@@ -0,0 +1,173 @@
+// Package trailer adds git trailers such as Co-authored-by and
+// Signed-off-by to the commit messages gix writes.
+package trailer
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/ademajagon/gix/internal/git"
+)
+
+// Trailer keys gix knows how to fill in.
+const (
+	CoAuthoredBy = "Co-authored-by"
+	SignedOffBy  = "Signed-off-by"
+	ChangeID     = "Change-Id"
+)
+
+// CoAuthor returns the Co-authored-by trailer for name, see ResolveAuthor.
+func CoAuthor(name string, aliases map[string]string) (string, error) {
+	authors, err := git.Authors()
+	if err != nil {
+		return "", err
+	}
+	ident, err := ResolveAuthor(name, aliases, authors)
+	if err != nil {
+		return "", err
+	}
+	return CoAuthoredBy + ": " + ident, nil
+}
+
+// ResolveAuthor turns name into "Name <email>". name may be an alias from
+// aliases, a full ident, or part of the name or email of exactly one of
+// authors, as listed by git.Authors.
+func ResolveAuthor(name string, aliases map[string]string, authors []string) (string, error) {
+	if ident, ok := aliases[name]; ok {
+		return ident, nil
+	}
+	if strings.Contains(name, "<") && strings.HasSuffix(name, ">") {
+		return name, nil
+	}
+
+	var matches []string
+	needle := strings.ToLower(name)
+	for _, a := range authors {
+		if a == "" {
+			continue
+		}
+		if strings.Contains(strings.ToLower(a), needle) {
+			matches = append(matches, a)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("unknown co-author %q, add it to co_authors in the config or pass \"Name <email>\"", name)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("co-author %q is ambiguous: %s", name, strings.Join(matches, ", "))
+	}
+}
+
+// Expand fills in the trailers configured as a key only: Signed-off-by gets
+// the committer and Change-Id a new Gerrit change id. Other trailers must
+// have a value.
+func Expand(trailers []string) ([]string, error) {
+	out := make([]string, 0, len(trailers))
+	for _, t := range trailers {
+		key, value, _ := strings.Cut(t, ":")
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if value == "" {
+			switch {
+			case strings.EqualFold(key, SignedOffBy):
+				ident, err := git.Var("GIT_COMMITTER_IDENT")
+				if err != nil {
+					return nil, err
+				}
+				value = stripDate(ident)
+			case strings.EqualFold(key, ChangeID):
+				value = newChangeID()
+			default:
+				return nil, fmt.Errorf("trailer %q has no value", key)
+			}
+		}
+		out = append(out, key+": "+value)
+	}
+	return out, nil
+}
+
+// Apply adds trailers to message. Trailers the message already has are not
+// repeated, and a configured Change-Id never replaces an existing one.
+func Apply(message string, trailers []string) (string, error) {
+	if len(trailers) == 0 {
+		return message, nil
+	}
+
+	existing, err := Parse(message)
+	if err != nil {
+		return "", err
+	}
+	expanded, err := Expand(pending(existing, trailers))
+	if err != nil {
+		return "", err
+	}
+	args := []string{"--if-exists", "addIfDifferent"}
+	for _, t := range expanded {
+		args = append(args, "--trailer", t)
+	}
+	return git.InterpretTrailers(message, args...)
+}
+
+// Parse returns the trailers at the end of message, e.g. to carry them
+// over when a commit is amended or reworded.
+func Parse(message string) ([]string, error) {
+	out, err := git.InterpretTrailers(message, "--parse")
+	if err != nil || out == "" {
+		return nil, err
+	}
+	return strings.Split(out, "\n"), nil
+}
+
+// pending drops a configured Change-Id without value when the message or
+// another trailer, e.g. one carried over from an amended commit, has one.
+func pending(existing, trailers []string) []string {
+	var out []string
+	for _, t := range trailers {
+		key, value, _ := strings.Cut(t, ":")
+		if strings.EqualFold(strings.TrimSpace(key), ChangeID) && strings.TrimSpace(value) == "" &&
+			(hasKey(existing, ChangeID) || hasKey(withValue(trailers), ChangeID)) {
+			continue
+		}
+		out = append(out, t)
+	}
+	return out
+}
+
+func withValue(trailers []string) []string {
+	var out []string
+	for _, t := range trailers {
+		if _, value, _ := strings.Cut(t, ":"); strings.TrimSpace(value) != "" {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+func hasKey(trailers []string, key string) bool {
+	for _, t := range trailers {
+		k, _, _ := strings.Cut(t, ":")
+		if strings.EqualFold(strings.TrimSpace(k), key) {
+			return true
+		}
+	}
+	return false
+}
+
+// stripDate drops the timestamp git var appends to an ident.
+func stripDate(ident string) string {
+	if i := strings.LastIndex(ident, ">"); i >= 0 {
+		return ident[:i+1]
+	}
+	return ident
+}
+
+// newChangeID returns a random id in Gerrit's format, "I" and 40 hex
+// digits. Gerrit derives it from the commit, any unique value works.
+func newChangeID() string {
+	b := make([]byte, 20)
+	rand.Read(b)
+	return "I" + hex.EncodeToString(b)
+}
@@ -0,0 +1,88 @@
+package trailer
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestResolveAuthor(t *testing.T) {
+	aliases := map[string]string{"al": "Alice Liddell <alice@example.com>"}
+	authors := []string{
+		"Alice Liddell <alice@example.com>",
+		"Bob Stone <bob@example.com>",
+		"Bobby Tables <tables@example.org>",
+	}
+
+	tests := []struct {
+		name    string
+		want    string
+		wantErr string
+	}{
+		{name: "al", want: "Alice Liddell <alice@example.com>"},
+		{name: "Carol <carol@example.com>", want: "Carol <carol@example.com>"},
+		{name: "alice", want: "Alice Liddell <alice@example.com>"},
+		{name: "tables@", want: "Bobby Tables <tables@example.org>"},
+		{name: "bob", wantErr: "ambiguous"},
+		{name: "dave", wantErr: "unknown co-author"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ResolveAuthor(tt.name, aliases, authors)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpand_ChangeID(t *testing.T) {
+	got, err := Expand([]string{"Change-Id", "Team: core"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !regexp.MustCompile(`^Change-Id: I[0-9a-f]{40}$`).MatchString(got[0]) {
+		t.Errorf("change id = %q", got[0])
+	}
+	if got[1] != "Team: core" {
+		t.Errorf("got %q, want the trailer unchanged", got[1])
+	}
+}
+
+func TestExpand_KeyWithoutValue(t *testing.T) {
+	if _, err := Expand([]string{"Reviewed-by"}); err == nil {
+		t.Fatal("expected an error for a trailer without value")
+	}
+}
+
+func TestPending_KeepsExistingChangeID(t *testing.T) {
+	configured := []string{"Signed-off-by", "Change-Id"}
+
+	if got := pending(nil, configured); !reflect.DeepEqual(got, configured) {
+		t.Errorf("no existing trailers: got %v", got)
+	}
+	if got := pending([]string{"Change-Id: Iabc"}, configured); !reflect.DeepEqual(got, []string{"Signed-off-by"}) {
+		t.Errorf("message has a Change-Id: got %v", got)
+	}
+
+	kept := append([]string{"Change-Id: Iabc"}, configured...)
+	if got := pending(nil, kept); !reflect.DeepEqual(got, []string{"Change-Id: Iabc", "Signed-off-by"}) {
+		t.Errorf("carried over Change-Id: got %v", got)
+	}
+}
+
+func TestStripDate(t *testing.T) {
+	if got := stripDate("Alice <alice@example.com> 1700000000 +0100"); got != "Alice <alice@example.com>" {
+		t.Errorf("got %q", got)
+	}
+}
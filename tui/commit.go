@@ -0,0 +1,229 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrCancelled is returned when the user quits without accepting.
+var ErrCancelled = errors.New("cancelled")
+
+// CommitActions are the slow operations the commit screen triggers. They
+// run while the screen shows a status line, Ctrl-C or Esc cancels their
+// context.
+type CommitActions struct {
+	// Regenerate asks for new suggestions.
+	Regenerate func(ctx context.Context) ([]string, error)
+	// Feedback asks for suggestions that take feedback on previous into
+	// account.
+	Feedback func(ctx context.Context, previous, feedback string) ([]string, error)
+	// Edit runs $EDITOR on msg, the terminal is suspended meanwhile.
+	Edit func(msg string) string
+}
+
+type commitAction int
+
+const (
+	commitNone commitAction = iota
+	commitAccept
+	commitEdit
+	commitRegenerate
+	commitFeedback
+	commitCancel
+)
+
+// commitView is the state of the commit screen: the diff on the left,
+// the suggestions and a preview of the selected one on the right.
+type commitView struct {
+	diff        []string
+	suggestions []string
+	selected    int
+	scroll      int
+	status      string
+	input       *lineInput
+	feedback    string // confirmed input, read by RunCommit
+}
+
+func newCommitView(diff string, suggestions []string) *commitView {
+	return &commitView{
+		diff:        strings.Split(strings.TrimRight(diff, "\n"), "\n"),
+		suggestions: suggestions,
+	}
+}
+
+// handle applies a key press, page is how many diff lines fit the screen.
+func (v *commitView) handle(k Key, page int) commitAction {
+	if v.input != nil {
+		done, ok := v.input.handle(k)
+		if !done {
+			return commitNone
+		}
+		text := strings.TrimSpace(string(v.input.text))
+		v.input = nil
+		if !ok || text == "" {
+			return commitNone
+		}
+		v.feedback = text
+		return commitFeedback
+	}
+
+	v.status = ""
+	switch k.Code {
+	case KeyEnter:
+		return commitAccept
+	case KeyEsc, KeyCtrlC:
+		return commitCancel
+	case KeyUp:
+		v.selected = max(v.selected-1, 0)
+	case KeyDown, KeyTab:
+		v.selected = min(v.selected+1, len(v.suggestions)-1)
+	case KeyPageDown:
+		v.scroll += page
+	case KeyPageUp:
+		v.scroll = max(v.scroll-page, 0)
+	case KeyRune:
+		switch r := k.Rune; {
+		case r >= '1' && r <= '9' && int(r-'1') < len(v.suggestions):
+			v.selected = int(r - '1')
+		case r == 'k':
+			v.selected = max(v.selected-1, 0)
+		case r == 'j':
+			v.selected = min(v.selected+1, len(v.suggestions)-1)
+		case r == ' ', r == 'J':
+			v.scroll += page
+		case r == 'K':
+			v.scroll = max(v.scroll-page, 0)
+		case r == 'e':
+			return commitEdit
+		case r == 'r':
+			return commitRegenerate
+		case r == 'f':
+			v.input = &lineInput{prompt: "feedback: "}
+		case r == 'c', r == 'q':
+			return commitCancel
+		}
+	}
+	return commitNone
+}
+
+// setSuggestions replaces the suggestions, keeping the selection in range.
+func (v *commitView) setSuggestions(s []string) {
+	v.suggestions = s
+	v.selected = min(v.selected, len(s)-1)
+}
+
+func (v *commitView) render(width, height int) []string {
+	bodyHeight := max(height-2, 1)
+	leftWidth := width * 3 / 5
+	rightWidth := max(width-leftWidth-1, 0)
+
+	diff, offset := window(v.diff, v.scroll, bodyHeight)
+	v.scroll = offset
+	left := make([]string, len(diff))
+	for i, l := range diff {
+		left[i] = diffLine(l, leftWidth)
+	}
+
+	var right []string
+	for i, s := range v.suggestions {
+		subject, _, _ := strings.Cut(s, "\n")
+		line := fit(fmt.Sprintf(" %d) %s", i+1, subject), rightWidth)
+		if i == v.selected {
+			line = styled(styleReverse, line)
+		}
+		right = append(right, line)
+	}
+	if len(v.suggestions) > 0 {
+		right = append(right, fit("", rightWidth))
+		for _, l := range strings.Split(v.suggestions[v.selected], "\n") {
+			right = append(right, fit(" "+l, rightWidth))
+		}
+	}
+
+	title := fmt.Sprintf(" gix commit · diff %d-%d of %d", offset+1, offset+len(diff), len(v.diff))
+	lines := []string{styled(styleBold, fit(title, width))}
+	lines = append(lines, columns(left, right, leftWidth, bodyHeight)...)
+	return append(lines, v.footer(width))
+}
+
+func (v *commitView) footer(width int) string {
+	switch {
+	case v.input != nil:
+		return v.input.render(width)
+	case v.status != "":
+		return fit(v.status, width)
+	default:
+		return styled(styleDim, fit("enter commit · ↑↓ select · e edit · r regen · f feedback · space/pgdn scroll · q cancel", width))
+	}
+}
+
+// RunCommit shows diff next to the suggestions until the user accepts one,
+// which is returned, or cancels, which returns ErrCancelled.
+func RunCommit(ctx context.Context, t *Terminal, diff string, suggestions []string, a CommitActions) (string, error) {
+	v := newCommitView(diff, suggestions)
+	draw := func() {
+		w, h := t.Size()
+		t.Draw(v.render(w, h))
+	}
+
+	// busy shows msg while fn runs, and fn's error afterwards
+	busy := func(msg string, fn func(context.Context) ([]string, error)) {
+		v.status = msg
+		draw()
+		var s []string
+		err := t.busy(ctx, draw, func(ctx context.Context) error {
+			var err error
+			s, err = fn(ctx)
+			return err
+		})
+		if err != nil {
+			v.status = err.Error()
+			return
+		}
+		if len(s) == 0 {
+			v.status = "no suggestions returned"
+			return
+		}
+		v.status = ""
+		v.setSuggestions(s)
+	}
+
+	for {
+		draw()
+
+		k, err := t.ReadKey()
+		if err != nil {
+			return "", err
+		}
+		if k.Code == KeyResize {
+			continue
+		}
+
+		_, h := t.Size()
+		switch v.handle(k, max(h-3, 1)) {
+		case commitAccept:
+			return v.suggestions[v.selected], nil
+		case commitCancel:
+			return "", ErrCancelled
+		case commitEdit:
+			var edited string
+			if err := t.Suspend(func() { edited = a.Edit(v.suggestions[v.selected]) }); err != nil {
+				return "", err
+			}
+			if edited == "" {
+				v.status = "commit message cannot be empty"
+				continue
+			}
+			v.suggestions[v.selected] = edited
+		case commitRegenerate:
+			busy("generating…", a.Regenerate)
+		case commitFeedback:
+			previous := v.suggestions[v.selected]
+			busy("refining…", func(ctx context.Context) ([]string, error) {
+				return a.Feedback(ctx, previous, v.feedback)
+			})
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package tui
+
+import "unicode/utf8"
+
+// KeyCode identifies a key that is not a printable character.
+type KeyCode int
+
+const (
+	KeyRune KeyCode = iota // a printable character, see Key.Rune
+	KeyEnter
+	KeyEsc
+	KeyBackspace
+	KeyTab
+	KeyUp
+	KeyDown
+	KeyLeft
+	KeyRight
+	KeyPageUp
+	KeyPageDown
+	KeyHome
+	KeyEnd
+	KeyCtrlC
+	KeyUnknown
+	KeyResize // not a key: the terminal was resized, see Terminal.ReadKey
+)
+
+// Key is one key press.
+type Key struct {
+	Code KeyCode
+	Rune rune
+}
+
+// escapeKeys maps the CSI and SS3 sequences xterm-like terminals send.
+var escapeKeys = map[string]KeyCode{
+	"[A": KeyUp, "[B": KeyDown, "[C": KeyRight, "[D": KeyLeft,
+	"OA": KeyUp, "OB": KeyDown, "OC": KeyRight, "OD": KeyLeft,
+	"[H": KeyHome, "[F": KeyEnd, "OH": KeyHome, "OF": KeyEnd,
+	"[1~": KeyHome, "[4~": KeyEnd, "[5~": KeyPageUp, "[6~": KeyPageDown,
+}
+
+// parseKeys decodes the bytes of one read. Terminals write an escape
+// sequence in one go, so a read holds whole keys, several when pasting.
+func parseKeys(b []byte) []Key {
+	var keys []Key
+	for len(b) > 0 {
+		key, n := parseKey(b)
+		keys = append(keys, key)
+		b = b[n:]
+	}
+	return keys
+}
+
+func parseKey(b []byte) (Key, int) {
+	switch b[0] {
+	case '\r', '\n':
+		return Key{Code: KeyEnter}, 1
+	case '\t':
+		return Key{Code: KeyTab}, 1
+	case 127, 8:
+		return Key{Code: KeyBackspace}, 1
+	case 3:
+		return Key{Code: KeyCtrlC}, 1
+	case 27:
+		if len(b) == 1 || (b[1] != '[' && b[1] != 'O') {
+			return Key{Code: KeyEsc}, 1
+		}
+		// a sequence ends with its first letter or '~'
+		for i := 2; i < len(b); i++ {
+			if c := b[i]; c == '~' || (c >= 'A' && c <= 'Z') || (c >= 'a' && c <= 'z') {
+				if code, ok := escapeKeys[string(b[1:i+1])]; ok {
+					return Key{Code: code}, i + 1
+				}
+				return Key{Code: KeyUnknown}, i + 1
+			}
+		}
+		return Key{Code: KeyUnknown}, len(b)
+	}
+
+	if b[0] < 32 {
+		return Key{Code: KeyUnknown}, 1
+	}
+	r, n := utf8.DecodeRune(b)
+	return Key{Code: KeyRune, Rune: r}, n
+}
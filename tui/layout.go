@@ -0,0 +1,118 @@
+package tui
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	styleReset   = "\033[0m"
+	styleReverse = "\033[7m"
+	styleBold    = "\033[1m"
+	styleDim     = "\033[2m"
+	styleRed     = "\033[31m"
+	styleGreen   = "\033[32m"
+	styleCyan    = "\033[36m"
+)
+
+// fit expands tabs and cuts or pads s to exactly width columns. Wide
+// characters count as one column, close enough for code and paths.
+func fit(s string, width int) string {
+	if width <= 0 {
+		return ""
+	}
+	s = strings.ReplaceAll(s, "\t", "    ")
+	if n := utf8.RuneCountInString(s); n <= width {
+		return s + strings.Repeat(" ", width-n)
+	}
+	runes := []rune(s)
+	if width == 1 {
+		return string(runes[:1])
+	}
+	return string(runes[:width-1]) + "…"
+}
+
+func styled(style, s string) string {
+	return style + s + styleReset
+}
+
+// diffLine fits a diff line and colors it by its prefix.
+func diffLine(s string, width int) string {
+	line := fit(s, width)
+	switch {
+	case strings.HasPrefix(s, "+"):
+		return styled(styleGreen, line)
+	case strings.HasPrefix(s, "-"):
+		return styled(styleRed, line)
+	case strings.HasPrefix(s, "@@"):
+		return styled(styleCyan, line)
+	case strings.HasPrefix(s, "diff --git"):
+		return styled(styleBold, line)
+	}
+	return line
+}
+
+// window returns the height lines of lines starting at offset, clamping
+// offset so the last page is full.
+func window(lines []string, offset, height int) ([]string, int) {
+	offset = min(offset, len(lines)-height)
+	offset = max(offset, 0)
+	end := min(offset+height, len(lines))
+	return lines[offset:end], offset
+}
+
+// follow scrolls offset just enough for line cursor to be visible.
+func follow(offset, cursor, height int) int {
+	if cursor < offset {
+		return cursor
+	}
+	if cursor >= offset+height {
+		return cursor - height + 1
+	}
+	return offset
+}
+
+// columns joins two panes of fitted lines side by side, padding the
+// shorter one.
+func columns(left, right []string, leftWidth, height int) []string {
+	out := make([]string, height)
+	for i := range out {
+		l, r := strings.Repeat(" ", leftWidth), ""
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		out[i] = l + styled(styleDim, "│") + r
+	}
+	return out
+}
+
+// lineInput is a single line of text typed at the bottom of the screen.
+type lineInput struct {
+	prompt string
+	text   []rune
+}
+
+// handle applies k, reporting whether input is finished and, if so,
+// whether it was confirmed rather than cancelled.
+func (in *lineInput) handle(k Key) (done, ok bool) {
+	switch k.Code {
+	case KeyEnter:
+		return true, true
+	case KeyEsc, KeyCtrlC:
+		return true, false
+	case KeyBackspace:
+		if len(in.text) > 0 {
+			in.text = in.text[:len(in.text)-1]
+		}
+	case KeyRune:
+		in.text = append(in.text, k.Rune)
+	}
+	return false, false
+}
+
+func (in *lineInput) render(width int) string {
+	return fit(in.prompt+string(in.text)+"█", width)
+}
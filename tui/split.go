@@ -0,0 +1,405 @@
+package tui
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ademajagon/gix/internal/git"
+	"github.com/ademajagon/gix/split"
+)
+
+// SplitActions are the operations the split screen needs from outside.
+type SplitActions struct {
+	// Reword runs $EDITOR on a commit message, the terminal is suspended
+	// meanwhile.
+	Reword func(msg string) string
+	// FillMessages generates the messages of groups that have none. Ctrl-C
+	// or Esc cancels ctx.
+	FillMessages func(ctx context.Context, groups []split.HunkGroup) error
+}
+
+// splitRow is one line of the group tree: a group header when hunk is -1,
+// otherwise a hunk of that group. Group len(groups) holds dropped hunks.
+type splitRow struct {
+	group, hunk int
+}
+
+// splitView is the state of the split screen: the group tree on the left,
+// the diff of the hunk or group under the cursor on the right. A grabbed
+// hunk moves with the cursor, across groups, until it is dropped.
+type splitView struct {
+	groups  []split.HunkGroup
+	dropped []git.Hunk
+	keepAll bool // every hunk must stay in a commit
+
+	cursor     int
+	grabbed    bool
+	scroll     int
+	diffScroll int
+	status     string
+}
+
+type splitAction int
+
+const (
+	splitNone splitAction = iota
+	splitApply
+	splitCancel
+	splitReword
+	splitRegenerate
+)
+
+func (v *splitView) rows() []splitRow {
+	var rows []splitRow
+	for g := 0; g <= len(v.groups); g++ {
+		hunks := v.hunks(g)
+		if g == len(v.groups) && len(hunks) == 0 {
+			break
+		}
+		rows = append(rows, splitRow{g, -1})
+		for h := range hunks {
+			rows = append(rows, splitRow{g, h})
+		}
+	}
+	return rows
+}
+
+func (v *splitView) hunks(g int) []git.Hunk {
+	if g == len(v.groups) {
+		return v.dropped
+	}
+	return v.groups[g].Hunks
+}
+
+func (v *splitView) setHunks(g int, hunks []git.Hunk) {
+	if g == len(v.groups) {
+		v.dropped = hunks
+	} else {
+		v.groups[g].Hunks = hunks
+	}
+}
+
+func (v *splitView) current() splitRow {
+	rows := v.rows()
+	v.cursor = min(max(v.cursor, 0), len(rows)-1)
+	return rows[v.cursor]
+}
+
+// moveTo puts the cursor on the row of group g, hunk h.
+func (v *splitView) moveTo(g, h int) {
+	for i, r := range v.rows() {
+		if r.group == g && r.hunk == h {
+			v.cursor = i
+			return
+		}
+	}
+}
+
+// shift moves the grabbed hunk one step up or down: within its group, or
+// to the nearest end of the neighbouring group.
+func (v *splitView) shift(delta int) {
+	r := v.current()
+	hunks := v.hunks(r.group)
+	last := len(v.groups) // the hunks left unstaged
+	if v.keepAll {
+		last--
+	}
+
+	target := r.hunk + delta
+	if target >= 0 && target < len(hunks) {
+		hunks[r.hunk], hunks[target] = hunks[target], hunks[r.hunk]
+		v.moveTo(r.group, target)
+		return
+	}
+
+	g := r.group + delta
+	if g < 0 || g > last {
+		return
+	}
+	h := hunks[r.hunk]
+	v.setHunks(r.group, append(hunks[:r.hunk:r.hunk], hunks[r.hunk+1:]...))
+	if delta > 0 {
+		v.setHunks(g, append([]git.Hunk{h}, v.hunks(g)...))
+		v.moveTo(g, 0)
+	} else {
+		v.setHunks(g, append(v.hunks(g), h))
+		v.moveTo(g, len(v.hunks(g))-1)
+	}
+}
+
+// newGroup moves the hunk under the cursor into a new commit at the end,
+// its message is generated on apply.
+func (v *splitView) newGroup() {
+	r := v.current()
+	if r.hunk < 0 {
+		return
+	}
+	hunks := v.hunks(r.group)
+	h := hunks[r.hunk]
+	v.setHunks(r.group, append(hunks[:r.hunk:r.hunk], hunks[r.hunk+1:]...))
+	v.groups = append(v.groups, split.HunkGroup{Hunks: []git.Hunk{h}})
+	v.moveTo(len(v.groups)-1, 0)
+}
+
+// toggleDrop moves the hunk under the cursor out of its commit, or back
+// into the last commit when it was dropped.
+func (v *splitView) toggleDrop() {
+	r := v.current()
+	if r.hunk < 0 || v.keepAll {
+		if v.keepAll {
+			v.status = "every hunk must stay in a commit when splitting existing commits"
+		}
+		return
+	}
+	hunks := v.hunks(r.group)
+	h := hunks[r.hunk]
+	v.setHunks(r.group, append(hunks[:r.hunk:r.hunk], hunks[r.hunk+1:]...))
+	if r.group == len(v.groups) {
+		last := len(v.groups) - 1
+		v.groups[last].Hunks = append(v.groups[last].Hunks, h)
+		v.moveTo(last, len(v.groups[last].Hunks)-1)
+		return
+	}
+	v.dropped = append(v.dropped, h)
+	v.moveTo(len(v.groups), len(v.dropped)-1)
+}
+
+// plan returns the groups that still have hunks.
+func (v *splitView) plan() []split.HunkGroup {
+	var groups []split.HunkGroup
+	for _, g := range v.groups {
+		if len(g.Hunks) > 0 {
+			groups = append(groups, g)
+		}
+	}
+	return groups
+}
+
+func (v *splitView) handle(k Key, page int) splitAction {
+	v.status = ""
+	rows := len(v.rows())
+
+	switch k.Code {
+	case KeyEnter:
+		if v.grabbed {
+			v.grabbed = false
+			return splitNone
+		}
+		return splitApply
+	case KeyEsc:
+		if v.grabbed {
+			v.grabbed = false
+			return splitNone
+		}
+		return splitCancel
+	case KeyCtrlC:
+		return splitCancel
+	case KeyUp:
+		v.up()
+	case KeyDown:
+		v.down(rows)
+	case KeyPageDown:
+		v.diffScroll += page
+	case KeyPageUp:
+		v.diffScroll = max(v.diffScroll-page, 0)
+	case KeyRune:
+		switch k.Rune {
+		case 'k':
+			v.up()
+		case 'j':
+			v.down(rows)
+		case ' ':
+			if v.current().hunk >= 0 {
+				v.grabbed = !v.grabbed
+			}
+		case 'J':
+			v.diffScroll += page
+		case 'K':
+			v.diffScroll = max(v.diffScroll-page, 0)
+		case 'n':
+			v.newGroup()
+		case 'x':
+			v.toggleDrop()
+		case 'm':
+			if v.current().group < len(v.groups) {
+				return splitReword
+			}
+		case 'g':
+			if v.current().group < len(v.groups) {
+				return splitRegenerate
+			}
+		case 'y', 'a':
+			return splitApply
+		case 'q':
+			return splitCancel
+		}
+	}
+	return splitNone
+}
+
+func (v *splitView) up() {
+	if v.grabbed {
+		v.shift(-1)
+	} else {
+		v.cursor = max(v.cursor-1, 0)
+	}
+	v.diffScroll = 0
+}
+
+func (v *splitView) down(rows int) {
+	if v.grabbed {
+		v.shift(1)
+	} else {
+		v.cursor = min(v.cursor+1, rows-1)
+	}
+	v.diffScroll = 0
+}
+
+func (v *splitView) render(width, height int) []string {
+	bodyHeight := max(height-2, 1)
+	leftWidth := width * 2 / 5
+	rightWidth := max(width-leftWidth-1, 0)
+	cur := v.current()
+
+	var tree []string
+	for i, r := range v.rows() {
+		var line string
+		switch {
+		case r.hunk < 0 && r.group == len(v.groups):
+			line = fmt.Sprintf("▾ left unstaged (%d)", len(v.dropped))
+		case r.hunk < 0:
+			g := v.groups[r.group]
+			msg := g.Message
+			if msg == "" {
+				msg = "(message generated on apply)"
+			}
+			line = fmt.Sprintf("▾ %d. %s (%d)", r.group+1, msg, len(g.Hunks))
+		default:
+			h := v.hunks(r.group)[r.hunk]
+			mark := "   "
+			if i == v.cursor && v.grabbed {
+				mark = " » "
+			}
+			line = fmt.Sprintf("%s%s %s", mark, h.FilePath, h.Header)
+		}
+		line = fit(line, leftWidth)
+		if i == v.cursor {
+			line = styled(styleReverse, line)
+		} else if r.hunk < 0 {
+			line = styled(styleBold, line)
+		}
+		tree = append(tree, line)
+	}
+	v.scroll = follow(v.scroll, v.cursor, bodyHeight)
+	left, _ := window(tree, v.scroll, bodyHeight)
+
+	var diff []string
+	shown := v.hunks(cur.group)
+	if cur.hunk >= 0 {
+		shown = shown[cur.hunk : cur.hunk+1]
+	}
+	for _, h := range shown {
+		diff = append(diff, h.FilePath, h.Header)
+		diff = append(diff, h.Lines()...)
+		diff = append(diff, "")
+	}
+	diff, v.diffScroll = window(diff, v.diffScroll, bodyHeight)
+	right := make([]string, len(diff))
+	for i, l := range diff {
+		right[i] = diffLine(l, rightWidth)
+	}
+
+	title := fmt.Sprintf(" gix split · %d commit(s)", len(v.plan()))
+	if len(v.dropped) > 0 {
+		title += fmt.Sprintf(", %d hunk(s) left unstaged", len(v.dropped))
+	}
+	lines := []string{styled(styleBold, fit(title, width))}
+	lines = append(lines, columns(left, right, leftWidth, bodyHeight)...)
+
+	footer := "enter apply · ↑↓ move · space grab/drop hunk · n new commit · x unstage · m reword · g regen message · q cancel"
+	if v.grabbed {
+		footer = "↑↓ move the hunk between commits · space or enter to put it down"
+	}
+	if v.status != "" {
+		return append(lines, fit(v.status, width))
+	}
+	return append(lines, styled(styleDim, fit(footer, width)))
+}
+
+// RunSplit lets the user rearrange groups until they apply the plan, which
+// returns the non-empty groups and the hunks to leave unstaged, or cancel,
+// which returns ErrCancelled. With keepAll no hunk may be left out.
+func RunSplit(ctx context.Context, t *Terminal, groups []split.HunkGroup, keepAll bool, a SplitActions) ([]split.HunkGroup, []git.Hunk, error) {
+	v := &splitView{groups: groups, keepAll: keepAll}
+	draw := func() {
+		w, h := t.Size()
+		t.Draw(v.render(w, h))
+	}
+
+	// fill generates the missing messages of groups on a copy, the screen
+	// keeps drawing the groups meanwhile, and copies them back unless it
+	// failed or was cancelled
+	fill := func(msg string, groups []split.HunkGroup) bool {
+		v.status = msg
+		draw()
+		filled := append([]split.HunkGroup(nil), groups...)
+		err := t.busy(ctx, draw, func(ctx context.Context) error {
+			return a.FillMessages(ctx, filled)
+		})
+		if err != nil {
+			v.status = err.Error()
+			return false
+		}
+		for i := range groups {
+			groups[i].Message = filled[i].Message
+		}
+		v.status = ""
+		return true
+	}
+
+	for {
+		draw()
+
+		k, err := t.ReadKey()
+		if err != nil {
+			return nil, nil, err
+		}
+		if k.Code == KeyResize {
+			continue
+		}
+
+		_, h := t.Size()
+		switch v.handle(k, max(h-3, 1)) {
+		case splitApply:
+			plan := v.plan()
+			if len(plan) == 0 {
+				v.status = "plan has no commits"
+				continue
+			}
+			if fill("generating messages…", plan) {
+				return plan, v.dropped, nil
+			}
+		case splitCancel:
+			return nil, nil, ErrCancelled
+		case splitReword:
+			g := &v.groups[v.current().group]
+			var edited string
+			if err := t.Suspend(func() { edited = a.Reword(g.Message) }); err != nil {
+				return nil, nil, err
+			}
+			if edited != "" {
+				g.Message = edited
+			}
+		case splitRegenerate:
+			g := v.current().group
+			if len(v.groups[g].Hunks) == 0 {
+				continue
+			}
+			old := v.groups[g].Message
+			v.groups[g].Message = ""
+			if !fill("generating message…", v.groups[g:g+1]) {
+				v.groups[g].Message = old
+			}
+		}
+	}
+}
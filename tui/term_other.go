@@ -0,0 +1,30 @@
+//go:build !(linux || darwin || dragonfly || freebsd || netbsd || openbsd)
+
+package tui
+
+import (
+	"errors"
+	"os"
+)
+
+var errUnsupported = errors.New("terminal UI is not supported on this platform")
+
+type termState struct{}
+
+func isTerminal(uintptr) bool {
+	return false
+}
+
+func makeRaw(uintptr) (*termState, error) {
+	return nil, errUnsupported
+}
+
+func restore(uintptr, *termState) error {
+	return errUnsupported
+}
+
+func size(uintptr) (int, int, error) {
+	return 0, 0, errUnsupported
+}
+
+func notifyResize(chan<- os.Signal) {}
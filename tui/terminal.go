@@ -0,0 +1,171 @@
+// Package tui is a small full-screen terminal UI for `gix commit` and
+// `gix split`. It talks to the terminal with ioctl and ANSI sequences
+// directly, and only works on Unix-like systems; callers fall back to
+// line-based prompts when Open fails.
+package tui
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+)
+
+// Terminal is the controlling terminal in raw mode on the alternate
+// screen.
+type Terminal struct {
+	in, out *os.File
+	state   *termState
+	pending []Key
+	resize  chan os.Signal
+}
+
+// Available reports whether stdin and stdout are both terminals.
+func Available() bool {
+	return isTerminal(os.Stdin.Fd()) && isTerminal(os.Stdout.Fd())
+}
+
+// Open takes over the terminal. Close must be called to give it back.
+func Open() (*Terminal, error) {
+	if !Available() {
+		return nil, errors.New("not a terminal")
+	}
+	t := &Terminal{in: os.Stdin, out: os.Stdout}
+	if err := t.enter(); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+func (t *Terminal) enter() error {
+	state, err := makeRaw(t.in.Fd())
+	if err != nil {
+		return fmt.Errorf("raw mode: %w", err)
+	}
+	t.state = state
+	t.resize = make(chan os.Signal, 1)
+	notifyResize(t.resize)
+	// alternate screen, hidden cursor
+	fmt.Fprint(t.out, "\033[?1049h\033[?25l")
+	return nil
+}
+
+func (t *Terminal) leave() {
+	fmt.Fprint(t.out, "\033[?25h\033[?1049l")
+	if t.resize != nil {
+		signal.Stop(t.resize)
+		t.resize = nil
+	}
+	if t.state != nil {
+		restore(t.in.Fd(), t.state)
+		t.state = nil
+	}
+}
+
+// Close restores the terminal and the screen as they were before Open.
+func (t *Terminal) Close() {
+	t.leave()
+}
+
+// Suspend gives the terminal back while fn runs, e.g. to open $EDITOR.
+func (t *Terminal) Suspend(fn func()) error {
+	t.leave()
+	fn()
+	return t.enter()
+}
+
+// Size returns the terminal width and height, 80x24 if unknown.
+func (t *Terminal) Size() (int, int) {
+	w, h, err := size(t.out.Fd())
+	if err != nil || w <= 0 || h <= 0 {
+		return 80, 24
+	}
+	return w, h
+}
+
+// Draw replaces the screen with lines, which must fit its size.
+func (t *Terminal) Draw(lines []string) {
+	var b strings.Builder
+	b.WriteString("\033[H")
+	for i, l := range lines {
+		if i > 0 {
+			b.WriteString("\r\n")
+		}
+		b.WriteString(l)
+		b.WriteString("\033[K")
+	}
+	b.WriteString("\033[J")
+	fmt.Fprint(t.out, b.String())
+}
+
+// ReadKey blocks until a key is pressed. A resize of the terminal is
+// returned as KeyResize, so the caller can redraw.
+func (t *Terminal) ReadKey() (Key, error) {
+	key, _, err := t.readKey(nil)
+	return key, err
+}
+
+// readKey is ReadKey that also gives up, with ok false, once done is
+// closed. Raw mode makes reads return after a tenth of a second without
+// input, so done is checked that often.
+func (t *Terminal) readKey(done <-chan struct{}) (key Key, ok bool, err error) {
+	for len(t.pending) == 0 {
+		select {
+		case <-done:
+			return Key{}, false, nil
+		case <-t.resize:
+			return Key{Code: KeyResize}, true, nil
+		default:
+		}
+
+		buf := make([]byte, 64)
+		n, err := t.in.Read(buf)
+		if err == io.EOF {
+			continue // no input yet
+		}
+		if err != nil {
+			return Key{}, false, err
+		}
+		t.pending = parseKeys(buf[:n])
+	}
+	key = t.pending[0]
+	t.pending = t.pending[1:]
+	return key, true, nil
+}
+
+// busy runs fn, redrawing with draw when the terminal is resized, until fn
+// returns or the user presses Ctrl-C or Esc. That cancels fn's context and
+// returns ErrCancelled once fn gave up. Other keys are ignored meanwhile.
+func (t *Terminal) busy(ctx context.Context, draw func(), fn func(context.Context) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var err error
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		err = fn(ctx)
+	}()
+
+	for {
+		key, ok, readErr := t.readKey(done)
+		if !ok && readErr == nil {
+			return err
+		}
+		switch {
+		case readErr != nil:
+			cancel()
+			<-done
+			return readErr
+		case key.Code == KeyCtrlC, key.Code == KeyEsc:
+			cancel()
+			<-done
+			return ErrCancelled
+		case key.Code == KeyResize:
+			draw()
+		}
+	}
+}
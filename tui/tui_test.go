@@ -0,0 +1,223 @@
+package tui
+
+import (
+	"context"
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/ademajagon/gix/internal/git"
+	"github.com/ademajagon/gix/split"
+)
+
+func TestParseKeys(t *testing.T) {
+	got := parseKeys([]byte("a\x1b[A\x1b[6~\r\x1b\x7fé\x03"))
+	want := []Key{
+		{Code: KeyRune, Rune: 'a'},
+		{Code: KeyUp},
+		{Code: KeyPageDown},
+		{Code: KeyEnter},
+		{Code: KeyEsc},
+		{Code: KeyBackspace},
+		{Code: KeyRune, Rune: 'é'},
+		{Code: KeyCtrlC},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseKeys() = %+v, want %+v", got, want)
+	}
+}
+
+func TestFit(t *testing.T) {
+	cases := []struct {
+		in    string
+		width int
+		want  string
+	}{
+		{"abc", 5, "abc  "},
+		{"abcdef", 4, "abc…"},
+		{"\tx", 6, "    x "},
+		{"héllo", 5, "héllo"},
+	}
+	for _, c := range cases {
+		if got := fit(c.in, c.width); got != c.want {
+			t.Errorf("fit(%q, %d) = %q, want %q", c.in, c.width, got, c.want)
+		}
+	}
+}
+
+func runes(s string) []Key {
+	keys := make([]Key, 0, len(s))
+	for _, r := range s {
+		keys = append(keys, Key{Code: KeyRune, Rune: r})
+	}
+	return keys
+}
+
+func TestCommitView_SelectAndFeedback(t *testing.T) {
+	v := newCommitView("diff --git a/a b/a\n+x\n", []string{"feat: a", "fix: b", "chore: c"})
+
+	v.handle(Key{Code: KeyRune, Rune: '3'}, 10)
+	v.handle(Key{Code: KeyUp}, 10)
+	if v.selected != 1 {
+		t.Fatalf("expected the second suggestion selected, got %d", v.selected)
+	}
+
+	if a := v.handle(Key{Code: KeyRune, Rune: 'f'}, 10); a != commitNone || v.input == nil {
+		t.Fatal("expected f to open the feedback input")
+	}
+	for _, k := range runes("use api scope") {
+		v.handle(k, 10)
+	}
+	// q is text while typing, not cancel
+	if a := v.handle(Key{Code: KeyRune, Rune: 'q'}, 10); a != commitNone {
+		t.Fatalf("expected typing, got action %v", a)
+	}
+	v.handle(Key{Code: KeyBackspace}, 10)
+	if a := v.handle(Key{Code: KeyEnter}, 10); a != commitFeedback || v.feedback != "use api scope" {
+		t.Errorf("expected feedback %q, got action %v with %q", "use api scope", a, v.feedback)
+	}
+
+	if a := v.handle(Key{Code: KeyEnter}, 10); a != commitAccept {
+		t.Errorf("expected enter to accept, got %v", a)
+	}
+
+	lines := v.render(80, 10)
+	if len(lines) != 10 {
+		t.Errorf("expected a full screen of 10 lines, got %d", len(lines))
+	}
+}
+
+func groupsOf(sizes ...int) []split.HunkGroup {
+	var groups []split.HunkGroup
+	n := 0
+	for _, size := range sizes {
+		var g split.HunkGroup
+		for i := 0; i < size; i++ {
+			n++
+			g.Hunks = append(g.Hunks, git.Hunk{FilePath: string(rune('a' + n - 1))})
+		}
+		groups = append(groups, g)
+	}
+	return groups
+}
+
+func paths(hunks []git.Hunk) string {
+	var s string
+	for _, h := range hunks {
+		s += h.FilePath
+	}
+	return s
+}
+
+func TestSplitView_MovesGrabbedHunkBetweenGroups(t *testing.T) {
+	v := &splitView{groups: groupsOf(2, 1)}
+
+	// rows: header 1, a, b, header 2, c
+	v.handle(Key{Code: KeyDown}, 10)
+	v.handle(Key{Code: KeyRune, Rune: ' '}, 10)
+	v.handle(Key{Code: KeyDown}, 10)
+	v.handle(Key{Code: KeyDown}, 10)
+	v.handle(Key{Code: KeyDown}, 10)
+	v.handle(Key{Code: KeyEnter}, 10)
+
+	if got := paths(v.groups[0].Hunks) + "|" + paths(v.groups[1].Hunks); got != "b|ca" {
+		t.Fatalf("expected a at the end of the second commit, got %s", got)
+	}
+	if r := v.current(); r.group != 1 || r.hunk != 1 || v.grabbed {
+		t.Errorf("expected the cursor on the dropped-off hunk, got %+v grabbed=%v", r, v.grabbed)
+	}
+
+	// moving past the last commit leaves the hunk unstaged
+	v.handle(Key{Code: KeyRune, Rune: ' '}, 10)
+	v.handle(Key{Code: KeyDown}, 10)
+	if paths(v.dropped) != "a" {
+		t.Errorf("expected a to be left unstaged, got %q", paths(v.dropped))
+	}
+}
+
+func TestSplitView_NewGroupAndPlan(t *testing.T) {
+	v := &splitView{groups: groupsOf(1, 2)}
+	v.moveTo(1, 1)
+	v.handle(Key{Code: KeyRune, Rune: 'n'}, 10)
+	v.moveTo(0, 0)
+	v.handle(Key{Code: KeyRune, Rune: 'x'}, 10)
+
+	plan := v.plan()
+	if len(plan) != 2 || paths(plan[0].Hunks) != "b" || paths(plan[1].Hunks) != "c" {
+		t.Errorf("expected the emptied commit to be dropped from the plan, got %+v", plan)
+	}
+	if plan[1].Message != "" {
+		t.Error("expected the new commit to have no message yet")
+	}
+	if paths(v.dropped) != "a" {
+		t.Errorf("expected a to be left unstaged, got %q", paths(v.dropped))
+	}
+}
+
+func TestSplitView_KeepAll(t *testing.T) {
+	v := &splitView{groups: groupsOf(1), keepAll: true}
+	v.moveTo(0, 0)
+
+	v.handle(Key{Code: KeyRune, Rune: 'x'}, 10)
+	v.handle(Key{Code: KeyRune, Rune: ' '}, 10)
+	v.handle(Key{Code: KeyDown}, 10)
+	if len(v.dropped) != 0 || len(v.groups[0].Hunks) != 1 {
+		t.Errorf("expected every hunk to stay in a commit, got %+v dropped %+v", v.groups, v.dropped)
+	}
+}
+
+// pipeTerminal returns a terminal reading keys from a pipe, and its write end.
+func pipeTerminal(t *testing.T) (*Terminal, *os.File) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close(); w.Close() })
+	return &Terminal{in: r, resize: make(chan os.Signal, 1)}, w
+}
+
+func TestTerminal_BusyCancels(t *testing.T) {
+	term, keys := pipeTerminal(t)
+	redrawn := 0
+	term.resize <- os.Interrupt
+
+	err := term.busy(context.Background(), func() { redrawn++ }, func(ctx context.Context) error {
+		keys.WriteString("x\x1b")
+		<-ctx.Done()
+		return ctx.Err()
+	})
+	if !errors.Is(err, ErrCancelled) {
+		t.Errorf("expected ErrCancelled, got %v", err)
+	}
+	if redrawn != 1 {
+		t.Errorf("expected one redraw after the resize, got %d", redrawn)
+	}
+}
+
+func TestTerminal_BusyReturnsResult(t *testing.T) {
+	term, keys := pipeTerminal(t)
+	want := errors.New("no model")
+
+	// a pipe, unlike the terminal, blocks until input, keep the reader awake
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			case <-time.After(10 * time.Millisecond):
+				keys.WriteString("x")
+			}
+		}
+	}()
+
+	err := term.busy(context.Background(), func() {}, func(context.Context) error {
+		return want
+	})
+	if err != want {
+		t.Errorf("expected fn's error, got %v", err)
+	}
+}
@@ -2,31 +2,41 @@ package utils
 
 import (
 	"fmt"
+	"os"
 	"sync/atomic"
 	"time"
 )
 
 type Spinner struct {
 	stopFlag int32
+	message  atomic.Value // string shown after the frame
 }
 
 func NewSpinner() *Spinner {
 	return &Spinner{}
 }
 
-// Start begins animating the spinner in a background goroutine
+// Start begins animating the spinner in a background goroutine. It draws on
+// stderr so that command output on stdout stays clean when redirected.
 func (s *Spinner) Start() {
 	frames := []string{"⠋", "⠙", "⠹", "⠸", "⠼", "⠴", "⠦", "⠧", "⠇", "⠏"}
 	go func() {
 		for i := 0; atomic.LoadInt32(&s.stopFlag) == 0; i++ {
-			fmt.Printf("\r%s", frames[i%len(frames)])
+			msg, _ := s.message.Load().(string)
+			fmt.Fprintf(os.Stderr, "\r%s %s\033[K", frames[i%len(frames)], msg)
 			time.Sleep(100 * time.Millisecond)
 		}
 	}()
 }
 
+// SetMessage changes the text shown next to the spinner, e.g. progress.
+// Safe to call from any goroutine.
+func (s *Spinner) SetMessage(msg string) {
+	s.message.Store(msg)
+}
+
 // Stop terminates the spinner and clears the line
 func (s *Spinner) Stop() {
 	atomic.StoreInt32(&s.stopFlag, 1)
-	fmt.Print("\r\033[K")
+	fmt.Fprint(os.Stderr, "\r\033[K")
 }